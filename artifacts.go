@@ -0,0 +1,91 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/ozmodiar/kobo-highlights/formats"
+)
+
+// Page furniture sometimes leaks into highlights that span a page break. The
+// patterns below are deliberately narrow: each requires a run of two or more
+// spaces (or a line break) around the fragment, which ordinary prose rarely has.
+var (
+	// A page number alone on its own line: "...end of page\n42\nnext page...".
+	pageNumberLine = regexp.MustCompile(`(?m)^[ \t]*\d{1,4}[ \t]*(\n|$)`)
+	// A page number followed by an ALL-CAPS running header: "42   CHAPTER THREE   ...".
+	numberThenHeader = regexp.MustCompile(`(^|\s+)\d{1,4}[ \t]{2,}\p{Lu}[\p{Lu}\p{N} '’.,:-]*?\p{Lu}([ \t]{2,}|\n|$)`)
+	// An ALL-CAPS running header followed by a page number: "THE HOBBIT   42   ...".
+	headerThenNumber = regexp.MustCompile(`(^|\s+)\p{Lu}[\p{Lu}\p{N} '’.,:-]*?\p{Lu}[ \t]{2,}\d{1,4}([ \t]{2,}|\n|$)`)
+)
+
+// cleanArtifacts strips page numbers and running headers from highlight text (experimental).
+func cleanArtifacts(books []formats.Book) []formats.Book {
+	for i := range books {
+		repeated := repeatedHeaderLines(books[i].Highlights)
+		for j := range books[i].Highlights {
+			h := &books[i].Highlights[j]
+			if cleaned := cleanHighlightText(h.Text, repeated); cleaned != h.Text {
+				h.Text = cleaned
+				h.Runs = nil // emphasis offsets no longer line up with the cleaned text
+			}
+		}
+	}
+	return books
+}
+
+// cleanHighlightText applies the page furniture patterns and drops repeated header lines.
+func cleanHighlightText(s string, repeated map[string]bool) string {
+	if len(repeated) > 0 {
+		lines := strings.Split(s, "\n")
+		kept := lines[:0]
+		for _, l := range lines {
+			if !repeated[strings.TrimSpace(l)] {
+				kept = append(kept, l)
+			}
+		}
+		s = strings.Join(kept, "\n")
+	}
+	s = numberThenHeader.ReplaceAllString(s, " ")
+	s = headerThenNumber.ReplaceAllString(s, " ")
+	s = pageNumberLine.ReplaceAllString(s, "")
+	return strings.TrimSpace(s)
+}
+
+// repeatedHeaderLines finds ALL-CAPS lines that recur in two or more highlights of
+// the same book – the signature of a running header without a page number.
+func repeatedHeaderLines(highlights []formats.Highlight) map[string]bool {
+	counts := map[string]int{}
+	for _, h := range highlights {
+		seen := map[string]bool{}
+		for _, l := range strings.Split(h.Text, "\n") {
+			l = strings.TrimSpace(l)
+			if isHeaderLine(l) && !seen[l] {
+				seen[l] = true
+				counts[l]++
+			}
+		}
+	}
+	repeated := map[string]bool{}
+	for l, n := range counts {
+		if n >= 2 {
+			repeated[l] = true
+		}
+	}
+	return repeated
+}
+
+// isHeaderLine reports whether a line has at least four letters and none are lowercase.
+func isHeaderLine(l string) bool {
+	letters := 0
+	for _, r := range l {
+		if unicode.IsLower(r) {
+			return false
+		}
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	return letters >= 4
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// applyConfig loads flag defaults from a TOML file. Keys are flag names; keys under a
+// [table] get the table name as prefix ("token" in [notion] is --notion-token). Values
+// only apply to flags not given on the command line or through their environment
+// variable, so the order is CLI > env > config file > built-in default.
+func applyConfig(c *cli.Context, path string) error {
+	values, err := parseConfigFile(path)
+	if err != nil {
+		return err
+	}
+	known := map[string]bool{}
+	for _, f := range c.App.Flags {
+		for _, n := range f.Names() {
+			known[n] = true
+		}
+	}
+	for _, kv := range values {
+		if !known[kv.key] {
+			return fmt.Errorf("%s:%d: unknown option %q", path, kv.line, kv.key)
+		}
+		if c.IsSet(kv.key) {
+			continue
+		}
+		for _, v := range kv.values {
+			if err := c.Set(kv.key, v); err != nil {
+				return fmt.Errorf("%s:%d: %s: %w", path, kv.line, kv.key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// configValue is one key of a config file with its value(s) as flag strings.
+type configValue struct {
+	key    string
+	values []string // one entry, or one per array element
+	line   int
+}
+
+// parseConfigFile reads the TOML subset used for config files: [table] headers,
+// key = value pairs with basic strings, integers, floats, booleans and single-line
+// arrays of those, and # comments.
+func parseConfigFile(path string) ([]configValue, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open config: %w", err)
+	}
+	defer f.Close()
+	var out []configValue
+	table := ""
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(stripTOMLComment(sc.Text()))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("%s:%d: invalid table header %q", path, n, line)
+			}
+			table = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected key = value", path, n)
+		}
+		key = strings.Trim(strings.TrimSpace(key), `"`)
+		if table != "" {
+			key = table + "-" + key
+		}
+		values, err := parseTOMLValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s: %w", path, n, key, err)
+		}
+		out = append(out, configValue{key: key, values: values, line: n})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	return out, nil
+}
+
+// parseTOMLValue converts a scalar or single-line array to flag value strings.
+func parseTOMLValue(raw string) ([]string, error) {
+	if strings.HasPrefix(raw, "[") {
+		if !strings.HasSuffix(raw, "]") {
+			return nil, fmt.Errorf("arrays must be on one line")
+		}
+		var values []string
+		rest := strings.TrimSpace(raw[1 : len(raw)-1])
+		for rest != "" {
+			item, tail, err := nextTOMLItem(rest)
+			if err != nil {
+				return nil, err
+			}
+			v, err := parseTOMLScalar(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+			rest = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tail), ","))
+		}
+		return values, nil
+	}
+	v, err := parseTOMLScalar(raw)
+	if err != nil {
+		return nil, err
+	}
+	return []string{v}, nil
+}
+
+// nextTOMLItem splits the first array element (up to a comma outside quotes) off s.
+func nextTOMLItem(s string) (item, rest string, err error) {
+	if strings.HasPrefix(s, `"`) {
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '"':
+				return s[:i+1], s[i+1:], nil
+			}
+		}
+		return "", "", fmt.Errorf("unterminated string")
+	}
+	item, rest, _ = strings.Cut(s, ",")
+	return strings.TrimSpace(item), rest, nil
+}
+
+// parseTOMLScalar returns a basic string unquoted, and integers, floats and booleans as written.
+func parseTOMLScalar(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		s, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		return s, nil
+	case strings.HasPrefix(raw, "'"):
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		return raw[1 : len(raw)-1], nil // literal string: no escapes
+	case raw == "true" || raw == "false":
+		return raw, nil
+	}
+	if _, err := strconv.ParseFloat(strings.ReplaceAll(raw, "_", ""), 64); err == nil {
+		return strings.ReplaceAll(raw, "_", ""), nil
+	}
+	return "", fmt.Errorf("unsupported value %s (use a quoted string, number, boolean or array)", raw)
+}
+
+// stripTOMLComment removes a # comment that is not inside a string.
+func stripTOMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch ch := line[i]; {
+		case quote == 0 && (ch == '"' || ch == '\''):
+			quote = ch
+		case quote != 0 && ch == '\\' && quote == '"':
+			i++
+		case ch == quote:
+			quote = 0
+		case quote == 0 && ch == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
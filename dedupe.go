@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ozmodiar/kobo-highlights/formats"
+)
+
+// loadHashStore reads a file of highlight hashes (one per line); a missing file is an empty store.
+func loadHashStore(path string) (map[string]bool, error) {
+	store := map[string]bool{}
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("open hash store: %w", err)
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if h := strings.TrimSpace(sc.Text()); h != "" {
+			store[h] = true
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read hash store %s: %w", path, err)
+	}
+	return store, nil
+}
+
+// saveHashStore writes the store atomically (temp file in the same directory + rename).
+func saveHashStore(path string, store map[string]bool) error {
+	hashes := make([]string, 0, len(store))
+	for h := range store {
+		hashes = append(hashes, h)
+	}
+	sort.Strings(hashes)
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create hash store temp file: %w", err)
+	}
+	w := bufio.NewWriter(tmp)
+	for _, h := range hashes {
+		fmt.Fprintln(w, h)
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("write hash store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("close hash store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("replace hash store: %w", err)
+	}
+	return nil
+}
+
+// skipSeen drops highlights whose hash is already in store; books left empty are dropped.
+func skipSeen(books []formats.Book, store map[string]bool) []formats.Book {
+	out := make([]formats.Book, 0, len(books))
+	for _, b := range books {
+		kept := make([]formats.Highlight, 0, len(b.Highlights))
+		for _, h := range b.Highlights {
+			if !store[formats.HighlightHash(b, h)] {
+				kept = append(kept, h)
+			}
+		}
+		if len(kept) > 0 {
+			b.Highlights = kept
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// recordHashes adds the hash of every highlight in books to store.
+func recordHashes(books []formats.Book, store map[string]bool) {
+	for _, b := range books {
+		for _, h := range b.Highlights {
+			store[formats.HighlightHash(b, h)] = true
+		}
+	}
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+
+	"github.com/ozmodiar/kobo-highlights/formats"
+)
+
+// Reading progress filter modes.
+const (
+	progressAll        = ""
+	progressFinished   = "finished"
+	progressInProgress = "in-progress"
+)
+
+// progressFilterMode maps the mutually exclusive progress flags to a filter mode.
+func progressFilterMode(onlyFinished, onlyInProgress bool) (string, error) {
+	switch {
+	case onlyFinished && onlyInProgress:
+		return "", fmt.Errorf("--only-finished and --only-in-progress are mutually exclusive")
+	case onlyFinished:
+		return progressFinished, nil
+	case onlyInProgress:
+		return progressInProgress, nil
+	}
+	return progressAll, nil
+}
+
+// filterByProgress keeps books matching the progress mode relative to threshold (percent).
+func filterByProgress(books []formats.Book, mode string, threshold int) []formats.Book {
+	if mode == progressAll {
+		return books
+	}
+	out := make([]formats.Book, 0, len(books))
+	for _, b := range books {
+		finished := b.Progress >= threshold
+		switch mode {
+		case progressFinished:
+			if finished {
+				out = append(out, b)
+			}
+		case progressInProgress:
+			if b.Progress > 0 && !finished {
+				out = append(out, b)
+			}
+		}
+	}
+	return out
+}
+
+// filterByMinProgress drops books read less than min percent (0 keeps all).
+func filterByMinProgress(books []formats.Book, min int) []formats.Book {
+	if min <= 0 {
+		return books
+	}
+	out := make([]formats.Book, 0, len(books))
+	for _, b := range books {
+		if b.Progress >= min {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// limitBooks keeps whole books, in order, until at least limit highlights are included.
+// The last book kept may take the total past limit; books are never split.
+func limitBooks(books []formats.Book, limit int) []formats.Book {
+	if limit <= 0 {
+		return books
+	}
+	total := 0
+	for i, b := range books {
+		if total >= limit {
+			return books[:i]
+		}
+		total += len(b.Highlights)
+	}
+	return books
+}
+
+// limitPerBook keeps the first limit highlights of each book, in their current order.
+func limitPerBook(books []formats.Book, limit int) []formats.Book {
+	if limit <= 0 {
+		return books
+	}
+	for i := range books {
+		if len(books[i].Highlights) > limit {
+			books[i].Highlights = books[i].Highlights[:limit:limit]
+		}
+	}
+	return books
+}
+
+// searchMatcher returns the --search predicate: a case-insensitive substring test, or
+// with asRegex a case-insensitive regular expression match.
+func searchMatcher(query string, asRegex bool) (func(string) bool, error) {
+	if asRegex {
+		re, err := regexp.Compile("(?i)" + query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --search regular expression: %w", err)
+		}
+		return re.MatchString, nil
+	}
+	query = strings.ToLower(query)
+	return func(text string) bool { return strings.Contains(strings.ToLower(text), query) }, nil
+}
+
+// searchHighlights keeps highlights whose text satisfies match plus up to context
+// neighbours on each side within the same book, in reading order. Overlapping
+// windows are merged; books without matches are dropped.
+func searchHighlights(books []formats.Book, match func(string) bool, context int) []formats.Book {
+	out := make([]formats.Book, 0, len(books))
+	for _, b := range books {
+		keep := make([]bool, len(b.Highlights))
+		matched := false
+		for i, h := range b.Highlights {
+			if !match(h.Text) {
+				continue
+			}
+			matched = true
+			for j := max(i-context, 0); j <= min(i+context, len(b.Highlights)-1); j++ {
+				keep[j] = true
+			}
+		}
+		if !matched {
+			continue
+		}
+		kept := make([]formats.Highlight, 0, len(b.Highlights))
+		for i, h := range b.Highlights {
+			if keep[i] {
+				kept = append(kept, h)
+			}
+		}
+		b.Highlights = kept
+		out = append(out, b)
+	}
+	return out
+}
+
+// Within-book highlight orders for --sort.
+const (
+	sortPosition = "position"
+	sortDate     = "date"
+)
+
+// Book orders for --sort books=….
+const (
+	sortBooksTitle  = "title"
+	sortBooksAuthor = "author"
+	sortBooksRecent = "recent"
+	sortBooksCount  = "count"
+)
+
+// sortSpec is a parsed --sort value.
+type sortSpec struct {
+	within string // sortPosition or sortDate
+	books  string // one of the sortBooks… orders
+}
+
+// parseSortFlag accepts comma-separated "within-book=position|date" and
+// "books=title|author|recent|count" parts (a bare position or date is the within-book order).
+// Without a within-book part highlights stay in reading position order, or are ordered
+// by date when merged is set: highlights merged from several devices have no common
+// reading order.
+func parseSortFlag(v string, merged bool) (sortSpec, error) {
+	spec := sortSpec{within: sortPosition, books: sortBooksTitle}
+	if merged {
+		spec.within = sortDate
+	}
+	for _, part := range strings.Split(strings.ToLower(v), ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			key, value = "within-book", key
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch {
+		case key == "within-book" && value == "":
+		case key == "within-book" && (value == sortPosition || value == sortDate):
+			spec.within = value
+		case key == "books" && (value == sortBooksTitle || value == sortBooksAuthor || value == sortBooksRecent || value == sortBooksCount):
+			spec.books = value
+		default:
+			return sortSpec{}, fmt.Errorf("--sort parts must be within-book=position|date or books=title|author|recent|count (got %q)", strings.TrimSpace(part))
+		}
+	}
+	return spec, nil
+}
+
+// sortBooks orders books for --sort books=…: title keeps the source's title order,
+// author sorts by author then title, recent puts the book with the newest highlight first
+// (books without parsed dates last) and count the most highlighted first. Ties keep
+// title order; names are compared with the collation of locale.
+func sortBooks(books []formats.Book, order string, locale language.Tag) []formats.Book {
+	if order == sortBooksTitle {
+		return books
+	}
+	c := collate.New(locale)
+	type entry struct {
+		book   formats.Book
+		latest time.Time
+	}
+	entries := make([]entry, len(books))
+	for i, b := range books {
+		entries[i].book = b
+		entries[i].latest = latestHighlight(b.Highlights)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch order {
+		case sortBooksAuthor:
+			if r := c.CompareString(a.book.Author, b.book.Author); r != 0 {
+				return r < 0
+			}
+		case sortBooksRecent:
+			if !a.latest.Equal(b.latest) {
+				return a.latest.After(b.latest)
+			}
+		case sortBooksCount:
+			if len(a.book.Highlights) != len(b.book.Highlights) {
+				return len(a.book.Highlights) > len(b.book.Highlights)
+			}
+		}
+		return c.CompareString(a.book.Title, b.book.Title) < 0
+	})
+	for i, e := range entries {
+		books[i] = e.book
+	}
+	return books
+}
+
+// sortWithinBooks orders each book's highlights. Sources return reading position order
+// (a merging source: each input's in turn), so only date ordering (oldest first;
+// unparsed dates last) changes anything.
+func sortWithinBooks(books []formats.Book, order string) []formats.Book {
+	if order != sortDate {
+		return books
+	}
+	for i := range books {
+		hs := append([]formats.Highlight(nil), books[i].Highlights...)
+		sort.SliceStable(hs, func(a, b int) bool {
+			ta, tb := hs[a].Time, hs[b].Time
+			if ta.IsZero() || tb.IsZero() {
+				return !ta.IsZero() && tb.IsZero()
+			}
+			return ta.Before(tb)
+		})
+		books[i].Highlights = hs
+	}
+	return books
+}
+
+// latestHighlight returns the newest parsed highlight date (zero if none parsed).
+func latestHighlight(highlights []formats.Highlight) time.Time {
+	var latest time.Time
+	for _, h := range highlights {
+		if h.Time.After(latest) {
+			latest = h.Time
+		}
+	}
+	return latest
+}
+
+// parseDateBound parses a --since/--until value: RFC3339, or YYYY-MM-DD in loc. A date-only
+// --until covers that whole day, so it is returned as the start of the next day.
+func parseDateBound(flag, v string, loc *time.Location, until bool) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", v, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --%s %q (want RFC3339 or YYYY-MM-DD)", flag, v)
+	}
+	if until {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// filterByDate keeps highlights created at or after since and before until (zero bounds
+// are open). Highlights whose date could not be parsed are dropped, as are empty books.
+func filterByDate(books []formats.Book, since, until time.Time) []formats.Book {
+	if since.IsZero() && until.IsZero() {
+		return books
+	}
+	out := make([]formats.Book, 0, len(books))
+	for _, b := range books {
+		kept := make([]formats.Highlight, 0, len(b.Highlights))
+		for _, h := range b.Highlights {
+			if h.Time.IsZero() || (!since.IsZero() && h.Time.Before(since)) || (!until.IsZero() && !h.Time.Before(until)) {
+				continue
+			}
+			kept = append(kept, h)
+		}
+		if len(kept) > 0 {
+			b.Highlights = kept
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// filterByMinLength drops highlights whose trimmed text has fewer than min runes (stray
+// taps selecting a word or a character) and books left without highlights; min <= 0 keeps all.
+func filterByMinLength(books []formats.Book, min int) []formats.Book {
+	if min <= 0 {
+		return books
+	}
+	out := make([]formats.Book, 0, len(books))
+	for _, b := range books {
+		kept := make([]formats.Highlight, 0, len(b.Highlights))
+		for _, h := range b.Highlights {
+			if utf8.RuneCountInString(strings.TrimSpace(h.Text)) >= min {
+				kept = append(kept, h)
+			}
+		}
+		if len(kept) > 0 {
+			b.Highlights = kept
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// filterByColor keeps highlights of the given color and the books that still have
+// highlights; an empty color keeps all.
+func filterByColor(books []formats.Book, color string) []formats.Book {
+	if color == "" {
+		return books
+	}
+	out := make([]formats.Book, 0, len(books))
+	for _, b := range books {
+		kept := make([]formats.Highlight, 0, len(b.Highlights))
+		for _, h := range b.Highlights {
+			if h.Color == color {
+				kept = append(kept, h)
+			}
+		}
+		if len(kept) > 0 {
+			b.Highlights = kept
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// filterByNotes keeps highlights with a (non-blank) note and the books that still
+// have highlights; with notesOnly false it keeps all.
+func filterByNotes(books []formats.Book, notesOnly bool) []formats.Book {
+	if !notesOnly {
+		return books
+	}
+	out := make([]formats.Book, 0, len(books))
+	for _, b := range books {
+		kept := make([]formats.Highlight, 0, len(b.Highlights))
+		for _, h := range b.Highlights {
+			if strings.TrimSpace(h.Note) != "" {
+				kept = append(kept, h)
+			}
+		}
+		if len(kept) > 0 {
+			b.Highlights = kept
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// filterByBook keeps books whose title and author contain the given substrings
+// (case-insensitive); an empty substring matches every book.
+func filterByBook(books []formats.Book, title, author string) []formats.Book {
+	title, author = strings.ToLower(title), strings.ToLower(author)
+	if title == "" && author == "" {
+		return books
+	}
+	out := make([]formats.Book, 0, len(books))
+	for _, b := range books {
+		if strings.Contains(strings.ToLower(b.Title), title) && strings.Contains(strings.ToLower(b.Author), author) {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// bookExcluder returns a matcher for books whose title matches one of titles or whose
+// author matches one of authors, or nil when both are empty. Patterns are compared
+// case-insensitively against the whole value and may use the * and ? wildcards.
+func bookExcluder(titles, authors []string) func(formats.Book) bool {
+	compile := func(patterns []string) []*regexp.Regexp {
+		var res []*regexp.Regexp
+		for _, p := range patterns {
+			if p = strings.TrimSpace(p); p == "" {
+				continue
+			}
+			expr := regexp.QuoteMeta(p)
+			expr = strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(expr)
+			res = append(res, regexp.MustCompile(`(?is)^`+expr+`$`))
+		}
+		return res
+	}
+	titleRes, authorRes := compile(titles), compile(authors)
+	if len(titleRes) == 0 && len(authorRes) == 0 {
+		return nil
+	}
+	matches := func(res []*regexp.Regexp, s string) bool {
+		s = strings.TrimSpace(s)
+		for _, re := range res {
+			if re.MatchString(s) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(b formats.Book) bool {
+		return matches(titleRes, b.Title) || matches(authorRes, b.Author)
+	}
+}
+
+// excludeBooks drops the books excluded matches; a nil excluded keeps all.
+func excludeBooks(books []formats.Book, excluded func(formats.Book) bool) []formats.Book {
+	if excluded == nil {
+		return books
+	}
+	out := make([]formats.Book, 0, len(books))
+	for _, b := range books {
+		if !excluded(b) {
+			out = append(out, b)
+		}
+	}
+	return out
+}
@@ -0,0 +1,182 @@
+package formats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+)
+
+// AggregateFormat reports highlight counts per month (optionally per book) for charting.
+type AggregateFormat struct {
+	File   string // output path; "" or "-" writes to stdout
+	ByBook bool
+	JSON   bool // emit JSON instead of a text table
+
+	written []Output
+}
+
+// monthBucket is one row of the aggregate report.
+type monthBucket struct {
+	Month string `json:"month"` // YYYY-MM, or "unknown" when the date could not be parsed
+	Book  string `json:"book,omitempty"`
+	Count int    `json:"count"`
+}
+
+func (a *AggregateFormat) Name() string { return "aggregate" }
+
+// WritesStdout reports whether the report goes to stdout.
+func (a *AggregateFormat) WritesStdout() bool { return a.File == "" || a.File == "-" }
+
+// Outputs lists the report file once written (nothing for stdout).
+func (a *AggregateFormat) Outputs() []Output { return a.written }
+
+func (a *AggregateFormat) Export(books []Book) error {
+	buckets := a.aggregate(books)
+	var w io.Writer = os.Stdout
+	var file *os.File
+	if !a.WritesStdout() {
+		f, err := os.Create(a.File)
+		if err != nil {
+			return fmt.Errorf("create file %s: %w", a.File, err)
+		}
+		file, w = f, f
+	}
+	err := a.write(w, buckets)
+	if file != nil {
+		if cerr := file.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("close file %s: %w", a.File, cerr)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	a.recordOutput()
+	return nil
+}
+
+// write renders the buckets as JSON or as an aligned table.
+func (a *AggregateFormat) write(w io.Writer, buckets []monthBucket) error {
+	if a.JSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(buckets); err != nil {
+			return fmt.Errorf("write aggregate json: %w", err)
+		}
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if a.ByBook {
+		fmt.Fprintln(tw, "MONTH\tBOOK\tHIGHLIGHTS")
+	} else {
+		fmt.Fprintln(tw, "MONTH\tHIGHLIGHTS")
+	}
+	for _, b := range buckets {
+		if a.ByBook {
+			fmt.Fprintf(tw, "%s\t%s\t%d\n", b.Month, b.Book, b.Count)
+		} else {
+			fmt.Fprintf(tw, "%s\t%d\n", b.Month, b.Count)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("write aggregate table: %w", err)
+	}
+	return nil
+}
+
+func (a *AggregateFormat) recordOutput() {
+	if !a.WritesStdout() {
+		a.written = append(a.written, Output{Path: a.File})
+	}
+}
+
+// ValidateOutput re-parses a JSON report written to a file. Tables and stdout output
+// have nothing to re-read.
+func (a *AggregateFormat) ValidateOutput() error {
+	if !a.JSON || a.WritesStdout() {
+		return nil
+	}
+	data, err := os.ReadFile(a.File)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", a.File, err)
+	}
+	var buckets []monthBucket
+	if err := json.Unmarshal(data, &buckets); err != nil {
+		return fmt.Errorf("%s is not valid JSON: %w", a.File, err)
+	}
+	return nil
+}
+
+// aggregate buckets highlights into YYYY-MM (and book) counts, sorted by month then book.
+func (a *AggregateFormat) aggregate(books []Book) []monthBucket {
+	counts := map[monthBucket]int{}
+	for _, b := range books {
+		for _, h := range b.Highlights {
+			key := monthBucket{Month: "unknown"}
+			if !h.Time.IsZero() {
+				key.Month = h.Time.Format("2006-01")
+			}
+			if a.ByBook {
+				key.Book = b.Title
+				if b.Author != "" {
+					key.Book = fmt.Sprintf("%s (%s)", b.Title, b.Author)
+				}
+			}
+			counts[key]++
+		}
+	}
+	buckets := make([]monthBucket, 0, len(counts))
+	for k, n := range counts {
+		k.Count = n
+		buckets = append(buckets, k)
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].Month != buckets[j].Month {
+			return buckets[i].Month < buckets[j].Month
+		}
+		return buckets[i].Book < buckets[j].Book
+	})
+	return buckets
+}
+
+// registration
+type aggregateFileFlag struct{}
+
+func (aggregateFileFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "aggregate-file", Usage: "Output file for the monthly aggregate report (default: stdout)"}
+}
+
+type aggregateByBookFlag struct{}
+
+func (aggregateByBookFlag) CLIFlag() any {
+	return &cli.BoolFlag{Name: "aggregate-by-book", Usage: "Break monthly highlight counts down per book"}
+}
+
+type aggregateOutputFlag struct{}
+
+func (aggregateOutputFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "aggregate-output", Value: "table", Usage: "Aggregate report style: table or json"}
+}
+
+func init() {
+	RegisterFormat(&FormatFactory{
+		Name:  "aggregate",
+		Flags: []FlagProvider{aggregateFileFlag{}, aggregateByBookFlag{}, aggregateOutputFlag{}},
+		Build: func(r FlagValueResolver) (Format, error) {
+			output := strings.ToLower(strings.TrimSpace(r.String("aggregate-output")))
+			if output != "" && output != "table" && output != "json" {
+				return nil, fmt.Errorf("--aggregate-output must be table or json")
+			}
+			return &AggregateFormat{
+				File:   strings.TrimSpace(r.String("aggregate-file")),
+				ByBook: r.Bool("aggregate-by-book"),
+				JSON:   output == "json",
+			}, nil
+		},
+	})
+}
@@ -0,0 +1,105 @@
+package formats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// AnkiFormat writes a tab-separated file for Anki's "Import File": one note per highlight
+// with the book title and highlight number on the front, the highlight on the back and
+// the author as a tag.
+type AnkiFormat struct {
+	File    string // output path; "-" writes to stdout
+	written []Output
+}
+
+// ankiHeader holds Anki's file header directives. Lines starting with # are comments to
+// Anki versions that do not read them; newer ones take the separator and columns from here.
+const ankiHeader = "#separator:tab\n#html:false\n#columns:Front\tBack\tTags\n#tags column:3\n"
+
+func (a *AnkiFormat) Name() string { return "anki" }
+
+// WritesStdout reports whether the import file goes to stdout.
+func (a *AnkiFormat) WritesStdout() bool { return a.File == "-" }
+
+// Outputs lists the import file once written (nothing for stdout).
+func (a *AnkiFormat) Outputs() []Output { return a.written }
+
+func (a *AnkiFormat) Export(books []Book) error {
+	var out io.Writer = os.Stdout
+	var file io.WriteCloser
+	if !a.WritesStdout() {
+		f, err := createText(a.File, false)
+		if err != nil {
+			return fmt.Errorf("create file %s: %w", a.File, err)
+		}
+		file, out = f, f
+	}
+	w := bufio.NewWriter(out)
+	fmt.Fprint(w, ankiHeader)
+	for _, b := range books {
+		tag := ankiTag(b.Author)
+		for i, h := range b.Highlights {
+			text := ankiField(h.Text)
+			if text == "" {
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", ankiField(fmt.Sprintf("%s #%d", b.Title, i+1)), text, tag)
+		}
+	}
+	err := w.Flush()
+	if file != nil {
+		// Close also flushes an --output-encoding converter.
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("write anki file: %w", err)
+	}
+	if !a.WritesStdout() {
+		a.written = append(a.written, Output{Path: a.File})
+	}
+	return nil
+}
+
+// ankiField flattens a value onto one line (tabs and newlines would start a new field or
+// note) and quotes it CSV-style when it contains double quotes, as Anki's importer expects.
+func ankiField(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if strings.Contains(s, `"`) {
+		s = `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+	}
+	return s
+}
+
+// ankiTag turns an author into a single Anki tag (tags are space separated); "" for no author.
+func ankiTag(author string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(author, ",", " ")), "_")
+}
+
+// registration
+type ankiFileFlag struct{}
+
+func (ankiFileFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "anki-file", Usage: "Output file for the Anki import (tab-separated; - for stdout; required when --format anki)"}
+}
+
+func init() {
+	RegisterFormat(&FormatFactory{
+		Name:  "anki",
+		Flags: []FlagProvider{ankiFileFlag{}},
+		Build: func(r FlagValueResolver) (Format, error) {
+			file := strings.TrimSpace(r.String("anki-file"))
+			if file == "" {
+				return nil, fmt.Errorf("--anki-file required for format anki")
+			}
+			return &AnkiFormat{File: file}, nil
+		},
+	})
+}
@@ -0,0 +1,149 @@
+package formats
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// CalibreFormat writes one Calibre annotations file per book, in the shape of the
+// viewer's "Export annotations" JSON, named after the book's ISBN (for matching it
+// to the Calibre library entry) or, without one, its sanitized title and author.
+type CalibreFormat struct {
+	Dir     string
+	written []Output
+}
+
+// calibreCollection is the top-level object of a Calibre annotations export.
+type calibreCollection struct {
+	Type        string              `json:"type"` // always "calibre_annotation_collection"
+	Version     int                 `json:"version"`
+	Title       string              `json:"title,omitempty"`
+	Authors     []string            `json:"authors,omitempty"`
+	ISBN        string              `json:"isbn,omitempty"`
+	Annotations []calibreAnnotation `json:"annotations"`
+}
+
+// calibreAnnotation is one highlight. The device keeps no EPUB CFI positions, so the
+// highlight is identified by its text and chapter only.
+type calibreAnnotation struct {
+	Type            string   `json:"type"` // always "highlight"
+	UUID            string   `json:"uuid"`
+	HighlightedText string   `json:"highlighted_text"`
+	Notes           string   `json:"notes,omitempty"`
+	Timestamp       string   `json:"timestamp,omitempty"` // UTC, millisecond precision as Calibre writes it
+	TOCFamilyTitles []string `json:"toc_family_titles,omitempty"`
+}
+
+func (c *CalibreFormat) Name() string { return "calibre" }
+
+// Outputs lists the files written so far.
+func (c *CalibreFormat) Outputs() []Output { return c.written }
+
+func (c *CalibreFormat) Export(books []Book) error {
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return fmt.Errorf("create dir: %w", err)
+	}
+	used := map[string]bool{}
+	for _, b := range books {
+		base := calibreFilename(b)
+		name := base
+		for i := 2; used[strings.ToLower(name)]; i++ {
+			name = fmt.Sprintf("%s-%d", base, i)
+		}
+		used[strings.ToLower(name)] = true
+		data, err := json.MarshalIndent(calibreBook(b), "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode %s: %w", b.Title, err)
+		}
+		path := filepath.Join(c.Dir, name+".json")
+		if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+			return fmt.Errorf("write file %s: %w", path, err)
+		}
+		c.written = append(c.written, Output{Path: path})
+	}
+	return nil
+}
+
+// ValidateOutput reads every written file back and checks it is an annotation collection.
+func (c *CalibreFormat) ValidateOutput() error {
+	for _, out := range c.written {
+		data, err := os.ReadFile(out.Path)
+		if err != nil {
+			return fmt.Errorf("read calibre file %s: %w", out.Path, err)
+		}
+		var coll calibreCollection
+		if err := json.Unmarshal(data, &coll); err != nil {
+			return fmt.Errorf("%s: %w", out.Path, err)
+		}
+		if coll.Type != "calibre_annotation_collection" {
+			return fmt.Errorf("%s: not a calibre annotation collection", out.Path)
+		}
+	}
+	return nil
+}
+
+// calibreFilename is the book's ISBN (digits and X only) or its sanitized Title[-Author].
+func calibreFilename(b Book) string {
+	isbn := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' || r == 'X' || r == 'x' {
+			return r
+		}
+		return -1
+	}, b.ISBN)
+	if isbn != "" {
+		return strings.ToUpper(isbn)
+	}
+	if b.Author != "" {
+		return sanitizeFilename(b.Title + "-" + b.Author)
+	}
+	return sanitizeFilename(b.Title)
+}
+
+// calibreBook converts a book to a Calibre annotation collection.
+func calibreBook(b Book) calibreCollection {
+	coll := calibreCollection{Type: "calibre_annotation_collection", Version: 1, Title: b.Title, ISBN: b.ISBN, Annotations: []calibreAnnotation{}}
+	if b.Author != "" {
+		coll.Authors = []string{b.Author}
+	}
+	for _, h := range b.Highlights {
+		text := strings.TrimSpace(h.Text)
+		if text == "" {
+			continue
+		}
+		a := calibreAnnotation{Type: "highlight", UUID: HighlightHash(b, h)[:22], HighlightedText: text, Notes: strings.TrimSpace(h.Note)}
+		if !h.Time.IsZero() {
+			a.Timestamp = h.Time.UTC().Format("2006-01-02T15:04:05.000Z")
+		}
+		if h.Chapter != "" {
+			a.TOCFamilyTitles = []string{h.Chapter}
+		}
+		coll.Annotations = append(coll.Annotations, a)
+	}
+	return coll
+}
+
+// registration
+type calibreDirFlag struct{}
+
+func (calibreDirFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "calibre-dir", Usage: "Directory for Calibre annotation files, one <ISBN>.json per book (required when --format calibre)"}
+}
+
+func init() {
+	RegisterFormat(&FormatFactory{
+		Name:  "calibre",
+		Flags: []FlagProvider{calibreDirFlag{}},
+		Build: func(r FlagValueResolver) (Format, error) {
+			dir := strings.TrimSpace(r.String("calibre-dir"))
+			if dir == "" {
+				return nil, fmt.Errorf("--calibre-dir required for format calibre")
+			}
+			return &CalibreFormat{Dir: dir}, nil
+		},
+	})
+}
@@ -0,0 +1,34 @@
+package formats
+
+import (
+	"os"
+	"testing"
+)
+
+func TestCalibreValidateOutput(t *testing.T) {
+	c := &CalibreFormat{Dir: t.TempDir()}
+	books := []Book{
+		{Title: "Dune", Author: "Frank Herbert", ISBN: "978-0-441-17271-9", Highlights: []Highlight{{Text: "Fear is the mind-killer."}}},
+		{Title: "Emma \"Annotated\"", Highlights: []Highlight{{Text: "line one\nline two", Note: "a note"}}},
+	}
+	if err := c.Export(books); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.written) != 2 {
+		t.Fatalf("wrote %d files, want 2", len(c.written))
+	}
+	if err := c.ValidateOutput(); err != nil {
+		t.Fatalf("ValidateOutput rejected the written files: %v", err)
+	}
+	for name, doc := range map[string]string{
+		"truncated":  `{"type": "calibre_annotation_collection", "annotations": [`,
+		"other JSON": `{"books": []}`,
+	} {
+		if err := os.WriteFile(c.written[1].Path, []byte(doc), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.ValidateOutput(); err == nil {
+			t.Errorf("%s: ValidateOutput accepted %s", name, doc)
+		}
+	}
+}
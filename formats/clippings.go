@@ -0,0 +1,126 @@
+package formats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// ClippingsFormat writes a Kindle-style "My Clippings.txt" for tools that import Kindle highlights.
+type ClippingsFormat struct {
+	File       string // output path; "-" writes to stdout
+	appendMode bool
+	written    []Output
+}
+
+// Kindle clippings layout. Importers match these byte for byte, including CRLF line endings
+// and the byte order mark the device writes before every entry's title line.
+const (
+	clippingsBOM        = "\ufeff"
+	clippingsSeparator  = "=========="
+	clippingsEOL        = "\r\n"
+	clippingsDateLayout = "Monday, January 2, 2006 3:04:05 PM"
+)
+
+func (c *ClippingsFormat) Name() string { return "clippings" }
+
+// WritesStdout reports whether clippings go to stdout.
+func (c *ClippingsFormat) WritesStdout() bool { return c.File == "-" }
+
+// Outputs lists the clippings file once written (nothing for stdout).
+func (c *ClippingsFormat) Outputs() []Output { return c.written }
+
+// EnableAppend makes later exports add clippings to the end of the file, as a Kindle does.
+func (c *ClippingsFormat) EnableAppend() { c.appendMode = true }
+
+func (c *ClippingsFormat) Export(books []Book) error {
+	var out io.Writer = os.Stdout
+	var file *os.File
+	if !c.WritesStdout() {
+		f, err := openOutput(c.File, c.appendMode)
+		if err != nil {
+			return fmt.Errorf("create file %s: %w", c.File, err)
+		}
+		file, out = f, f
+	}
+	w := bufio.NewWriter(out)
+	for _, b := range books {
+		header := b.Title
+		if b.Author != "" {
+			header = fmt.Sprintf("%s (%s)", b.Title, b.Author)
+		}
+		// The device has no Kindle locations; the highlight's position in the book stands in.
+		for i, h := range b.Highlights {
+			text := clippingText(h.Text)
+			if text == "" {
+				continue
+			}
+			writeClipping(w, header, fmt.Sprintf("Your Highlight on Location %d-%d", i+1, i+1), clippingAdded(h), text)
+			if h.Note != "" {
+				writeClipping(w, header, fmt.Sprintf("Your Note on Location %d", i+1), clippingAdded(h), clippingText(h.Note))
+			}
+		}
+	}
+	err := w.Flush()
+	if file != nil {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("write clippings: %w", err)
+	}
+	if !c.WritesStdout() {
+		c.written = append(c.written, Output{Path: c.File})
+	}
+	return nil
+}
+
+// writeClipping writes one entry: header line, metadata line, blank line, text, separator.
+func writeClipping(w io.Writer, header, kind, added, text string) {
+	meta := "- " + kind
+	if added != "" {
+		meta += " | Added on " + added
+	}
+	for _, line := range []string{clippingsBOM + header, meta, "", text, clippingsSeparator} {
+		io.WriteString(w, line+clippingsEOL)
+	}
+}
+
+// clippingAdded formats the highlight time the way Kindle does; unparsed dates are passed through.
+func clippingAdded(h Highlight) string {
+	if h.Time.IsZero() {
+		return strings.TrimSpace(h.Date)
+	}
+	return h.Time.Format(clippingsDateLayout)
+}
+
+// clippingText flattens text onto one line, since importers read the text as a single line.
+func clippingText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// registration
+type clippingsFileFlag struct{}
+
+func (clippingsFileFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "clippings-file", Usage: "Output file for Kindle-style clippings, e.g. \"My Clippings.txt\" (- for stdout; required when --format clippings)"}
+}
+
+func init() {
+	RegisterFormat(&FormatFactory{
+		Name:  "clippings",
+		Flags: []FlagProvider{clippingsFileFlag{}},
+		Build: func(r FlagValueResolver) (Format, error) {
+			file := strings.TrimSpace(r.String("clippings-file"))
+			if file == "" {
+				return nil, fmt.Errorf("--clippings-file required for format clippings")
+			}
+			return &ClippingsFormat{File: file}, nil
+		},
+	})
+}
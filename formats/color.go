@@ -0,0 +1,31 @@
+package formats
+
+// highlightColors are the Kobo highlight colors in the order of Bookmark.Color (0–4).
+var highlightColors = []string{"yellow", "red", "green", "blue", "pink"}
+
+// KoboColorName maps a Bookmark.Color value to its color name ("" when out of range).
+func KoboColorName(code int64) string {
+	if code < 0 || code >= int64(len(highlightColors)) {
+		return ""
+	}
+	return highlightColors[code]
+}
+
+// IsHighlightColor reports whether name is one of the highlight color names.
+func IsHighlightColor(name string) bool {
+	for _, c := range highlightColors {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// colorEmoji marks a markdown quote with its highlight color.
+var colorEmoji = map[string]string{
+	"yellow": "🟡",
+	"red":    "🔴",
+	"green":  "🟢",
+	"blue":   "🔵",
+	"pink":   "🩷",
+}
@@ -0,0 +1,132 @@
+package formats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Open Library endpoints used for cover lookups.
+var (
+	openLibrarySearchURL    = "https://openlibrary.org/search.json"
+	openLibraryCoversURL    = "https://covers.openlibrary.org/b/id/%d-L.jpg"
+	openLibraryISBNCoverURL = "https://covers.openlibrary.org/b/isbn/%s-L.jpg"
+)
+
+// openLibrarySearchLimit is how many search results are checked for one with a cover.
+const openLibrarySearchLimit = 5
+
+// CoverLookup finds cover image URLs on Open Library by ISBN or by title and author.
+// Results, including misses, are cached for the run so each book is looked up once.
+type CoverLookup struct {
+	httpClient *http.Client
+	cache      map[string]string
+}
+
+func NewCoverLookup() *CoverLookup {
+	return &CoverLookup{httpClient: &http.Client{Timeout: 10 * time.Second}, cache: map[string]string{}}
+}
+
+// CoverURL returns a cover image URL for the book: the cover filed under its ISBN, else
+// the first title/author search result with a cover. It returns "" when none is found
+// or the lookup fails; covers are decorative, so errors are not reported. A lookup cut
+// short by ctx is not cached.
+func (c *CoverLookup) CoverURL(ctx context.Context, title, author, isbn string) string {
+	isbn = normalizeISBN(isbn)
+	key := strings.ToLower(title + "\x00" + author + "\x00" + isbn)
+	if u, ok := c.cache[key]; ok {
+		return u
+	}
+	u, err := c.isbnCover(ctx, isbn)
+	if u == "" && err == nil {
+		u, err = c.search(ctx, title, author)
+	}
+	if ctx.Err() == nil {
+		c.cache[key] = u
+	}
+	if err != nil {
+		return ""
+	}
+	return u
+}
+
+// normalizeISBN keeps the digits and check character X of an ISBN ("" if too short).
+func normalizeISBN(isbn string) string {
+	isbn = strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9':
+			return r
+		case r == 'x' || r == 'X':
+			return 'X'
+		}
+		return -1
+	}, isbn)
+	if len(isbn) != 10 && len(isbn) != 13 {
+		return ""
+	}
+	return isbn
+}
+
+// isbnCover returns the Covers API image for isbn if Open Library has one ("" if not).
+func (c *CoverLookup) isbnCover(ctx context.Context, isbn string) (string, error) {
+	if isbn == "" {
+		return "", nil
+	}
+	u := fmt.Sprintf(openLibraryISBNCoverURL, isbn)
+	// default=false makes a missing cover a 404 instead of a blank image.
+	req, err := http.NewRequestWithContext(ctx, "HEAD", u+"?default=false", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return u, nil
+	case http.StatusNotFound:
+		return "", nil
+	}
+	return "", fmt.Errorf("open library cover: %s", resp.Status)
+}
+
+// search queries the Open Library search API and returns the cover of the first of
+// its top results that has one.
+func (c *CoverLookup) search(ctx context.Context, title, author string) (string, error) {
+	q := url.Values{"title": {title}, "fields": {"cover_i"}, "limit": {fmt.Sprint(openLibrarySearchLimit)}}
+	if author != "" {
+		q.Set("author", author)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", openLibrarySearchURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("open library search: %s", resp.Status)
+	}
+	var result struct {
+		Docs []struct {
+			CoverID int `json:"cover_i"`
+		} `json:"docs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	for _, d := range result.Docs {
+		if d.CoverID != 0 {
+			return fmt.Sprintf(openLibraryCoversURL, d.CoverID), nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,84 @@
+package formats
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// stubOpenLibrary points the Open Library endpoints at a test server running handler
+// and returns a lookup using it; the real endpoints are restored when the test ends.
+func stubOpenLibrary(t *testing.T, handler http.HandlerFunc) *CoverLookup {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	search, covers, isbn := openLibrarySearchURL, openLibraryCoversURL, openLibraryISBNCoverURL
+	openLibrarySearchURL = srv.URL + "/search.json"
+	openLibraryCoversURL = srv.URL + "/b/id/%d-L.jpg"
+	openLibraryISBNCoverURL = srv.URL + "/b/isbn/%s-L.jpg"
+	t.Cleanup(func() { openLibrarySearchURL, openLibraryCoversURL, openLibraryISBNCoverURL = search, covers, isbn })
+	c := NewCoverLookup()
+	c.httpClient = srv.Client()
+	return c
+}
+
+func TestCoverLookup(t *testing.T) {
+	var requests []string
+	c := stubOpenLibrary(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.URL.Path {
+		case "/b/isbn/9780441172719-L.jpg":
+			if r.URL.Query().Get("default") != "false" {
+				t.Errorf("ISBN lookup without default=false: %s", r.URL)
+			}
+		case "/search.json":
+			if r.URL.Query().Get("limit") == "1" {
+				t.Errorf("search asks for a single result: %s", r.URL)
+			}
+			// The top result has no cover; the lookup must move on to the next.
+			w.Write([]byte(`{"docs": [{}, {"cover_i": 42}, {"cover_i": 7}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	ctx := context.Background()
+	if got, want := c.CoverURL(ctx, "Dune", "Frank Herbert", "978-0-441-17271-9"), fmt.Sprintf(openLibraryISBNCoverURL, "9780441172719"); got != want {
+		t.Errorf("ISBN cover = %q, want %q", got, want)
+	}
+	if len(requests) != 1 {
+		t.Errorf("ISBN hit made requests %v, want only the ISBN one", requests)
+	}
+	requests = nil
+	if got, want := c.CoverURL(ctx, "Dune Messiah", "Frank Herbert", "0-00-000000-0"), fmt.Sprintf(openLibraryCoversURL, 42); got != want {
+		t.Errorf("cover = %q, want the first search result with a cover (%q)", got, want)
+	}
+	if len(requests) != 2 {
+		t.Errorf("ISBN miss made requests %v, want the ISBN lookup then a search", requests)
+	}
+	requests = nil
+	c.CoverURL(ctx, "Dune Messiah", "Frank Herbert", "0-00-000000-0")
+	if len(requests) != 0 {
+		t.Errorf("cached lookup made requests %v", requests)
+	}
+}
+
+func TestCoverLookupCancelled(t *testing.T) {
+	var calls int
+	c := stubOpenLibrary(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"docs": [{"cover_i": 42}]}`))
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if got := c.CoverURL(ctx, "Dune", "", ""); got != "" {
+		t.Errorf("cancelled lookup returned %q", got)
+	}
+	if calls != 0 {
+		t.Errorf("cancelled lookup reached the server %d times", calls)
+	}
+	if got := c.CoverURL(context.Background(), "Dune", "", ""); got == "" {
+		t.Error("a cancelled lookup was cached as a miss")
+	}
+}
@@ -0,0 +1,34 @@
+package formats
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// koboDateLayouts are the zone-less DateCreated shapes written by Kobo firmware.
+// Fractional seconds (".000") are accepted by time.Parse without being in the layout.
+var koboDateLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+// ParseKoboDate parses a Bookmark.DateCreated value. The device stores wall-clock
+// time without a zone, so the value is interpreted in loc (the zone the device was
+// set to), or as UTC when loc is nil. Values that do carry an offset ("Z", "+02:00")
+// keep it.
+func ParseKoboDate(raw string, loc *time.Location) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if loc == nil {
+		loc = time.UTC
+	}
+	if t, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+		return t, nil
+	}
+	for _, layout := range koboDateLayouts {
+		if t, err := time.ParseInLocation(layout, raw, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised Kobo date %q", raw)
+}
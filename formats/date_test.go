@@ -0,0 +1,52 @@
+package formats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseKoboDate(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no tz database: %v", err)
+	}
+	tests := []struct {
+		name string
+		raw  string
+		loc  *time.Location
+		want time.Time
+	}{
+		{"milliseconds, no zone, UTC default", "2023-05-01T10:34:56.000", nil, time.Date(2023, 5, 1, 10, 34, 56, 0, time.UTC)},
+		{"non-zero milliseconds", "2023-05-01T10:34:56.789", nil, time.Date(2023, 5, 1, 10, 34, 56, 789e6, time.UTC)},
+		{"no milliseconds", "2023-05-01T10:34:56", nil, time.Date(2023, 5, 1, 10, 34, 56, 0, time.UTC)},
+		{"space separator", "2023-05-01 10:34:56", nil, time.Date(2023, 5, 1, 10, 34, 56, 0, time.UTC)},
+		{"space separator with milliseconds", "2023-05-01 10:34:56.000", nil, time.Date(2023, 5, 1, 10, 34, 56, 0, time.UTC)},
+		{"trailing Z", "2023-05-01T10:34:56Z", berlin, time.Date(2023, 5, 1, 10, 34, 56, 0, time.UTC)},
+		{"milliseconds and Z", "2023-05-01T10:34:56.000Z", berlin, time.Date(2023, 5, 1, 10, 34, 56, 0, time.UTC)},
+		{"explicit offset", "2023-05-01T12:34:56+02:00", nil, time.Date(2023, 5, 1, 10, 34, 56, 0, time.UTC)},
+		{"no zone in the device zone", "2023-05-01T12:34:56.000", berlin, time.Date(2023, 5, 1, 10, 34, 56, 0, time.UTC)},
+		{"surrounding whitespace", " 2023-05-01T10:34:56.000\n", nil, time.Date(2023, 5, 1, 10, 34, 56, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseKoboDate(tt.raw, tt.loc)
+			if err != nil {
+				t.Fatalf("ParseKoboDate(%q): %v", tt.raw, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseKoboDate(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+	if got, _ := ParseKoboDate("2023-05-01T10:34:56.000", nil); got.Location() != time.UTC {
+		t.Errorf("zone-less date without loc parsed in %v, want UTC", got.Location())
+	}
+}
+
+func TestParseKoboDateRejectsGarbage(t *testing.T) {
+	for _, raw := range []string{"", "yesterday", "2023-05-01", "01/05/2023 10:34"} {
+		if got, err := ParseKoboDate(raw, nil); err == nil {
+			t.Errorf("ParseKoboDate(%q) = %v, want an error", raw, got)
+		}
+	}
+}
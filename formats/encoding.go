@@ -0,0 +1,96 @@
+package formats
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// Output encodings for the files written by the markdown, text, anki and instapaper formats.
+const (
+	EncodingUTF8    = "utf8"     // UTF-8 without BOM (default)
+	EncodingUTF8BOM = "utf8-bom" // UTF-8 with a byte order mark, for tools that sniff it
+	EncodingUTF16LE = "utf16le"  // UTF-16 little-endian with BOM, as Windows "Unicode" text
+)
+
+var outputEncoding = EncodingUTF8
+
+// SetOutputEncoding selects the encoding of text files written from now on.
+func SetOutputEncoding(name string) error {
+	switch name {
+	case EncodingUTF8, EncodingUTF8BOM, EncodingUTF16LE:
+		outputEncoding = name
+		return nil
+	}
+	return fmt.Errorf("unknown output encoding %q (use %s, %s or %s)", name, EncodingUTF8, EncodingUTF8BOM, EncodingUTF16LE)
+}
+
+// createText opens path like openOutput and returns a writer converting UTF-8 to the
+// output encoding. The BOM, if any, is only written at the start of a file, not when
+// appending to one that already has content. Close flushes the encoder.
+func createText(path string, appendMode bool) (io.WriteCloser, error) {
+	fresh := true
+	if appendMode {
+		if st, err := os.Stat(path); err == nil && st.Size() > 0 {
+			fresh = false
+		}
+	}
+	f, err := openOutput(path, appendMode)
+	if err != nil {
+		return nil, err
+	}
+	switch outputEncoding {
+	case EncodingUTF8BOM:
+		if fresh {
+			if _, err := io.WriteString(f, "\ufeff"); err != nil {
+				f.Close()
+				return nil, err
+			}
+		}
+	case EncodingUTF16LE:
+		bom := unicode.UseBOM
+		if !fresh {
+			bom = unicode.IgnoreBOM
+		}
+		enc := unicode.UTF16(unicode.LittleEndian, bom).NewEncoder()
+		return &encodedFile{Writer: transform.NewWriter(f, enc), f: f}, nil
+	}
+	return f, nil
+}
+
+// encodedFile writes through an encoder into a file.
+type encodedFile struct {
+	*transform.Writer
+	f *os.File
+}
+
+func (e *encodedFile) Close() error {
+	if err := e.Writer.Close(); err != nil {
+		e.f.Close()
+		return err
+	}
+	return e.f.Close()
+}
+
+// writeTextFile is os.WriteFile for text in the output encoding.
+func writeTextFile(path string, data []byte) error {
+	f, err := createText(path, false)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// decodeText returns the contents of a text file written in any output encoding as
+// UTF-8, recognising the encoding by its BOM (no BOM means UTF-8).
+func decodeText(data []byte) ([]byte, error) {
+	out, _, err := transform.Bytes(unicode.BOMOverride(unicode.UTF8.NewDecoder()), data)
+	return out, err
+}
@@ -0,0 +1,15 @@
+package formats
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// HighlightHash returns a stable content hash for a highlight within its book.
+// Whitespace is normalised so re-flowed text from the device hashes the same.
+func HighlightHash(b Book, h Highlight) string {
+	norm := func(s string) string { return strings.Join(strings.Fields(s), " ") }
+	sum := sha256.Sum256([]byte(norm(b.Title) + "\x00" + norm(b.Author) + "\x00" + norm(h.Text)))
+	return hex.EncodeToString(sum[:])
+}
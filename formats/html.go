@@ -0,0 +1,136 @@
+package formats
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// HTMLFormat writes a single self-contained HTML page with a table of contents.
+type HTMLFormat struct {
+	File    string // output path; "-" writes to stdout
+	written []Output
+}
+
+func (h *HTMLFormat) Name() string { return "html" }
+
+// WritesStdout reports whether the page goes to stdout.
+func (h *HTMLFormat) WritesStdout() bool { return h.File == "-" }
+
+// Outputs lists the HTML file once written (nothing for stdout).
+func (h *HTMLFormat) Outputs() []Output { return h.written }
+
+var htmlPage = template.Must(template.New("page").Funcs(template.FuncMap{
+	"heading": bookHeading,
+	"quote":   htmlQuote,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Highlights</title>
+<style>
+body { font-family: Georgia, serif; max-width: 42em; margin: 2em auto; padding: 0 1em; line-height: 1.5; }
+blockquote { border-left: 3px solid #ccc; margin: 1em 0; padding-left: 1em; }
+.note { color: #555; margin: -0.5em 0 1em 1.3em; }
+</style>
+</head>
+<body>
+<h1>Highlights</h1>
+<nav>
+<ul>
+{{- range $i, $b := . }}
+<li><a href="#book-{{ $i }}">{{ heading $b }}</a></li>
+{{- end }}
+</ul>
+</nav>
+{{- range $i, $b := . }}
+<section id="book-{{ $i }}">
+<h2>{{ heading $b }}</h2>
+{{- range $b.Highlights }}
+<blockquote>{{ quote . }}</blockquote>
+{{- if .Note }}
+<p class="note"><strong>Note:</strong> {{ .Note }}</p>
+{{- end }}
+{{- end }}
+</section>
+{{- end }}
+</body>
+</html>
+`))
+
+func (h *HTMLFormat) Export(books []Book) error {
+	var w io.Writer = os.Stdout
+	var file *os.File
+	if !h.WritesStdout() {
+		f, err := os.Create(h.File)
+		if err != nil {
+			return fmt.Errorf("create file %s: %w", h.File, err)
+		}
+		file, w = f, f
+	}
+	err := htmlPage.Execute(w, books)
+	if file != nil {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("write html: %w", err)
+	}
+	if !h.WritesStdout() {
+		h.written = append(h.written, Output{Path: h.File})
+	}
+	return nil
+}
+
+// bookHeading is "Title (Author)", or just the title when the author is unknown.
+func bookHeading(b Book) string {
+	if b.Author == "" {
+		return b.Title
+	}
+	return fmt.Sprintf("%s (%s)", b.Title, b.Author)
+}
+
+// htmlQuote renders highlight text, with <strong>/<em> for emphasis runs; all text is escaped.
+func htmlQuote(h Highlight) template.HTML {
+	if len(h.Runs) == 0 {
+		return template.HTML(template.HTMLEscapeString(strings.TrimSpace(h.Text)))
+	}
+	var sb strings.Builder
+	for _, r := range h.Runs {
+		text := template.HTMLEscapeString(r.Text)
+		if r.Italic {
+			text = "<em>" + text + "</em>"
+		}
+		if r.Bold {
+			text = "<strong>" + text + "</strong>"
+		}
+		sb.WriteString(text)
+	}
+	return template.HTML(strings.TrimSpace(sb.String()))
+}
+
+// registration
+type htmlFileFlag struct{}
+
+func (htmlFileFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "html-file", Usage: "Output file for the HTML page (- for stdout; required when --format html)"}
+}
+
+func init() {
+	RegisterFormat(&FormatFactory{
+		Name:  "html",
+		Flags: []FlagProvider{htmlFileFlag{}},
+		Build: func(r FlagValueResolver) (Format, error) {
+			file := strings.TrimSpace(r.String("html-file"))
+			if file == "" {
+				return nil, fmt.Errorf("--html-file required for format html")
+			}
+			return &HTMLFormat{File: file}, nil
+		},
+	})
+}
@@ -0,0 +1,130 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// InstapaperFormat writes the highlight CSV that Instapaper and Matter import: one row
+// per highlight under a URL, Title, Selection, Note header.
+type InstapaperFormat struct {
+	File    string // output path; "-" writes to stdout
+	written []Output
+}
+
+// instapaperHeader is the exact header row the importers expect.
+var instapaperHeader = []string{"URL", "Title", "Selection", "Note"}
+
+func (i *InstapaperFormat) Name() string { return "instapaper" }
+
+// WritesStdout reports whether the CSV goes to stdout.
+func (i *InstapaperFormat) WritesStdout() bool { return i.File == "-" }
+
+// Outputs lists the CSV file once written (nothing for stdout).
+func (i *InstapaperFormat) Outputs() []Output { return i.written }
+
+func (i *InstapaperFormat) Export(books []Book) error {
+	var out io.Writer = os.Stdout
+	var file io.WriteCloser
+	if !i.WritesStdout() {
+		f, err := createText(i.File, false)
+		if err != nil {
+			return fmt.Errorf("create file %s: %w", i.File, err)
+		}
+		file, out = f, f
+	}
+	w := csv.NewWriter(out)
+	w.Write(instapaperHeader)
+	for _, b := range books {
+		link := instapaperURL(b)
+		for _, h := range b.Highlights {
+			text := strings.TrimSpace(h.Text)
+			if text == "" {
+				continue
+			}
+			w.Write([]string{link, b.Title, text, strings.TrimSpace(h.Note)})
+		}
+	}
+	w.Flush()
+	err := w.Error()
+	if file != nil {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("write instapaper csv: %w", err)
+	}
+	if !i.WritesStdout() {
+		i.written = append(i.written, Output{Path: i.File})
+	}
+	return nil
+}
+
+// ValidateOutput reads the CSV back through the output encoding and checks that it starts
+// with the import header and that every row has the header's columns.
+func (i *InstapaperFormat) ValidateOutput() error {
+	if i.WritesStdout() {
+		return nil
+	}
+	data, err := os.ReadFile(i.File)
+	if err != nil {
+		return fmt.Errorf("read instapaper csv %s: %w", i.File, err)
+	}
+	if data, err = decodeText(data); err != nil {
+		return fmt.Errorf("decode %s: %w", i.File, err)
+	}
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = len(instapaperHeader)
+	rows, err := r.ReadAll()
+	if err != nil {
+		return fmt.Errorf("%s: %w", i.File, err)
+	}
+	if len(rows) == 0 || !slices.Equal(rows[0], instapaperHeader) {
+		return fmt.Errorf("%s: missing header %s", i.File, strings.Join(instapaperHeader, ","))
+	}
+	return nil
+}
+
+// instapaperURL is the book's row URL, which the importers group highlights by: its
+// kobo.com store page for purchased books, else a stable kobo://book/<title>?author=
+// pseudo-URL, since sideloaded books have no web address.
+func instapaperURL(b Book) string {
+	if b.StoreURL != "" {
+		return b.StoreURL
+	}
+	u := url.URL{Scheme: "kobo", Host: "book", Path: "/" + b.Title}
+	if b.Author != "" {
+		u.RawQuery = url.Values{"author": {b.Author}}.Encode()
+	}
+	return u.String()
+}
+
+// registration
+type instapaperFileFlag struct{}
+
+func (instapaperFileFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "instapaper-file", Usage: "Output CSV for Instapaper or Matter import (- for stdout; required when --format instapaper)"}
+}
+
+func init() {
+	RegisterFormat(&FormatFactory{
+		Name:  "instapaper",
+		Flags: []FlagProvider{instapaperFileFlag{}},
+		Build: func(r FlagValueResolver) (Format, error) {
+			file := strings.TrimSpace(r.String("instapaper-file"))
+			if file == "" {
+				return nil, fmt.Errorf("--instapaper-file required for format instapaper")
+			}
+			return &InstapaperFormat{File: file}, nil
+		},
+	})
+}
@@ -0,0 +1,67 @@
+package formats
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestInstapaperHonoursOutputEncoding(t *testing.T) {
+	if err := SetOutputEncoding(EncodingUTF16LE); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetOutputEncoding(EncodingUTF8) })
+	path := filepath.Join(t.TempDir(), "highlights.csv")
+	i := &InstapaperFormat{File: path}
+	books := []Book{{Title: "Café", StoreURL: "https://www.kobo.com/ebook/cafe", Highlights: []Highlight{{Text: "crème brûlée"}}}}
+	if err := i.Export(books); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) < 2 || data[0] != 0xff || data[1] != 0xfe {
+		t.Fatalf("file does not start with a UTF-16LE BOM: % x", data[:min(len(data), 4)])
+	}
+	got, err := unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder().Bytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "URL,Title,Selection,Note\nhttps://www.kobo.com/ebook/cafe,Café,crème brûlée,\n"
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestInstapaperValidateOutput(t *testing.T) {
+	t.Cleanup(func() { SetOutputEncoding(EncodingUTF8) })
+	path := filepath.Join(t.TempDir(), "highlights.csv")
+	i := &InstapaperFormat{File: path}
+	books := []Book{{Title: `Dune, "Messiah"`, Highlights: []Highlight{{Text: "one,\ntwo", Note: `a "note"`}}}}
+	for _, enc := range []string{EncodingUTF8, EncodingUTF8BOM, EncodingUTF16LE} {
+		if err := SetOutputEncoding(enc); err != nil {
+			t.Fatal(err)
+		}
+		if err := i.Export(books); err != nil {
+			t.Fatal(err)
+		}
+		if err := i.ValidateOutput(); err != nil {
+			t.Errorf("%s: ValidateOutput rejected the written CSV: %v", enc, err)
+		}
+	}
+	for name, doc := range map[string]string{
+		"short row":      "URL,Title,Selection,Note\nkobo://book/Dune,Dune,text\n",
+		"no header":      "kobo://book/Dune,Dune,text,\n",
+		"unclosed quote": "URL,Title,Selection,Note\nkobo://book/Dune,Dune,\"text,\n",
+	} {
+		if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := i.ValidateOutput(); err == nil {
+			t.Errorf("%s: ValidateOutput accepted %q", name, doc)
+		}
+	}
+}
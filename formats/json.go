@@ -0,0 +1,125 @@
+package formats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// JSONFormat writes all books as one indented JSON array (the shape LoadJSON reads back).
+type JSONFormat struct {
+	File    string // output path; "-" writes to stdout
+	RFC3339 bool   // emit parsed dates as RFC3339 instead of the raw device string
+	written []Output
+}
+
+func (j *JSONFormat) Name() string { return "json" }
+
+// WritesStdout reports whether the JSON goes to stdout.
+func (j *JSONFormat) WritesStdout() bool { return j.File == "-" }
+
+// Outputs lists the JSON file once written (nothing for stdout).
+func (j *JSONFormat) Outputs() []Output { return j.written }
+
+func (j *JSONFormat) Export(books []Book) error {
+	if j.RFC3339 {
+		books = rfc3339Dates(books)
+	}
+	var w io.Writer = os.Stdout
+	var file *os.File
+	if !j.WritesStdout() {
+		f, err := os.Create(j.File)
+		if err != nil {
+			return fmt.Errorf("create file %s: %w", j.File, err)
+		}
+		file, w = f, f
+	}
+	// Keys follow struct field order, so output is stable across runs.
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(books)
+	if file != nil {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("write json: %w", err)
+	}
+	if !j.WritesStdout() {
+		j.written = append(j.written, Output{Path: j.File})
+	}
+	return nil
+}
+
+// ValidateOutput reads the written file back with LoadJSON.
+func (j *JSONFormat) ValidateOutput() error {
+	if j.WritesStdout() {
+		return nil
+	}
+	_, err := LoadJSON(j.File)
+	return err
+}
+
+// rfc3339Dates returns a copy of books with each parsed highlight date formatted as RFC3339;
+// dates that could not be parsed keep their raw value.
+func rfc3339Dates(books []Book) []Book {
+	out := make([]Book, len(books))
+	for i, b := range books {
+		hs := make([]Highlight, len(b.Highlights))
+		for k, h := range b.Highlights {
+			if !h.Time.IsZero() {
+				h.Date = h.Time.Format(time.RFC3339)
+			}
+			hs[k] = h
+		}
+		b.Highlights = hs
+		out[i] = b
+	}
+	return out
+}
+
+// LoadJSON reads a JSON export (an array of books) back into memory.
+func LoadJSON(path string) ([]Book, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read json %s: %w", path, err)
+	}
+	var books []Book
+	if err := json.Unmarshal(data, &books); err != nil {
+		return nil, fmt.Errorf("parse json %s: %w", path, err)
+	}
+	return books, nil
+}
+
+// registration
+type jsonFileFlag struct{}
+
+func (jsonFileFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "json-file", Usage: "Output file for JSON (- for stdout; required when --format json)"}
+}
+
+type jsonRFC3339Flag struct{}
+
+func (jsonRFC3339Flag) CLIFlag() any {
+	return &cli.BoolFlag{Name: "json-rfc3339", Usage: "Write highlight dates as RFC3339 timestamps (with --timezone offset)"}
+}
+
+func init() {
+	RegisterFormat(&FormatFactory{
+		Name:  "json",
+		Flags: []FlagProvider{jsonFileFlag{}, jsonRFC3339Flag{}},
+		Build: func(r FlagValueResolver) (Format, error) {
+			file := strings.TrimSpace(r.String("json-file"))
+			if file == "" {
+				return nil, fmt.Errorf("--json-file required for format json")
+			}
+			return &JSONFormat{File: file, RFC3339: r.Bool("json-rfc3339")}, nil
+		},
+	})
+}
@@ -0,0 +1,152 @@
+package formats
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// JSONLFormat writes newline-delimited JSON, one flat highlight object per line, for
+// jq and log pipelines. Lines can be appended, so it works with --watch.
+type JSONLFormat struct {
+	File       string // output path; "-" writes to stdout
+	appendMode bool
+	written    []Output
+	out        *bufio.Writer // open output while streaming
+	file       *os.File      // file behind out (nil for stdout)
+}
+
+// jsonlHighlight is one line: the highlight with its book's title and author.
+type jsonlHighlight struct {
+	Title   string `json:"title"`
+	Author  string `json:"author"`
+	Text    string `json:"text"`
+	Note    string `json:"note,omitempty"`
+	Chapter string `json:"chapter,omitempty"`
+	Color   string `json:"color,omitempty"`
+	Date    string `json:"date,omitempty"` // RFC3339 when parsed, else the raw device value
+}
+
+func (j *JSONLFormat) Name() string { return "jsonl" }
+
+// WritesStdout reports whether the lines go to stdout.
+func (j *JSONLFormat) WritesStdout() bool { return j.File == "-" }
+
+// Outputs lists the file once written (nothing for stdout).
+func (j *JSONLFormat) Outputs() []Output { return j.written }
+
+// EnableAppend makes later exports append lines to the existing file.
+func (j *JSONLFormat) EnableAppend() { j.appendMode = true }
+
+func (j *JSONLFormat) Export(books []Book) error {
+	if err := j.StartStream(); err != nil {
+		return err
+	}
+	for _, b := range books {
+		if err := j.StreamBook(b); err != nil {
+			j.EndStream()
+			return err
+		}
+	}
+	return j.EndStream()
+}
+
+// StartStream opens the file (or stdout).
+func (j *JSONLFormat) StartStream() error {
+	var out io.Writer = os.Stdout
+	if !j.WritesStdout() {
+		f, err := openOutput(j.File, j.appendMode)
+		if err != nil {
+			return fmt.Errorf("create file %s: %w", j.File, err)
+		}
+		j.file, out = f, f
+	}
+	j.out = bufio.NewWriter(out)
+	return nil
+}
+
+// StreamBook writes a line per highlight of b.
+func (j *JSONLFormat) StreamBook(b Book) error {
+	enc := json.NewEncoder(j.out) // Encode ends each value with a newline
+	for _, h := range b.Highlights {
+		line := jsonlHighlight{Title: b.Title, Author: b.Author, Text: h.Text, Note: h.Note, Chapter: h.Chapter, Color: h.Color, Date: h.Date}
+		if !h.Time.IsZero() {
+			line.Date = h.Time.Format(time.RFC3339)
+		}
+		if err := enc.Encode(line); err != nil {
+			return fmt.Errorf("write jsonl: %w", err)
+		}
+	}
+	return nil
+}
+
+// EndStream flushes and closes the output.
+func (j *JSONLFormat) EndStream() error {
+	if j.out == nil {
+		return nil
+	}
+	out, file := j.out, j.file
+	j.out, j.file = nil, nil
+	err := out.Flush()
+	if file != nil {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("write jsonl: %w", err)
+	}
+	if file != nil {
+		j.written = append(j.written, Output{Path: j.File})
+	}
+	return nil
+}
+
+// ValidateOutput checks that every line of the written file is a JSON object.
+func (j *JSONLFormat) ValidateOutput() error {
+	if j.WritesStdout() {
+		return nil
+	}
+	data, err := os.ReadFile(j.File)
+	if err != nil {
+		return fmt.Errorf("read jsonl %s: %w", j.File, err)
+	}
+	for i, line := range bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n")) {
+		if len(line) == 0 {
+			continue // an export without highlights writes nothing
+		}
+		var obj map[string]any
+		if err := json.Unmarshal(line, &obj); err != nil {
+			return fmt.Errorf("%s:%d: %w", j.File, i+1, err)
+		}
+	}
+	return nil
+}
+
+// registration
+type jsonlFileFlag struct{}
+
+func (jsonlFileFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "jsonl-file", Usage: "Output file for JSON Lines, one highlight per line (- for stdout; required when --format jsonl)"}
+}
+
+func init() {
+	RegisterFormat(&FormatFactory{
+		Name:  "jsonl",
+		Flags: []FlagProvider{jsonlFileFlag{}},
+		Build: func(r FlagValueResolver) (Format, error) {
+			file := strings.TrimSpace(r.String("jsonl-file"))
+			if file == "" {
+				return nil, fmt.Errorf("--jsonl-file required for format jsonl")
+			}
+			return &JSONLFormat{File: file}, nil
+		},
+	})
+}
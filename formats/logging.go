@@ -0,0 +1,80 @@
+package formats
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+// LogLevel selects how chatty the tool is; warnings and errors are logged at every level.
+type LogLevel int
+
+const (
+	LogQuiet   LogLevel = iota // warnings and errors only
+	LogNormal                  // plus progress messages and the console preview (default)
+	LogVerbose                 // plus each Notion request and each file written
+)
+
+var logLevel = LogNormal
+
+// SetLogLevel sets the level used by Infof and Verbosef.
+func SetLogLevel(l LogLevel) { logLevel = l }
+
+// CurrentLogLevel returns the level set by SetLogLevel.
+func CurrentLogLevel() LogLevel { return logLevel }
+
+// Infof prints a progress message to stderr unless the level is LogQuiet.
+func Infof(format string, args ...any) {
+	if logLevel >= LogNormal {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+}
+
+// Verbosef logs a detail message at LogVerbose.
+func Verbosef(format string, args ...any) {
+	if logLevel >= LogVerbose {
+		log.Printf(format, args...)
+	}
+}
+
+// progressOpen is set while a progress line waits on stderr without its newline.
+var progressOpen bool
+
+// stderrIsTerminal reports whether stderr is a terminal (not a pipe or file).
+func stderrIsTerminal() bool {
+	fi, err := os.Stderr.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// Progress shows "[ i/total ] label" on stderr for long loops (Notion pages, Readwise
+// batches), rewriting one line in place until i reaches total. It is silent at
+// LogQuiet and when stderr is not a terminal; at LogVerbose each step gets its own
+// line so request logs do not run into it.
+func Progress(i, total int, label string) {
+	if logLevel < LogNormal || !stderrIsTerminal() {
+		return
+	}
+	if r := []rune(label); len(r) > 60 { // keep the line from wrapping, which breaks \r
+		label = string(r[:59]) + "…"
+	}
+	width := len(fmt.Sprint(total))
+	line := fmt.Sprintf("[ %*d/%d ] %s", width, i, total, label)
+	if logLevel >= LogVerbose {
+		fmt.Fprintln(os.Stderr, line)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\r\033[K%s", line)
+	progressOpen = i < total
+	if !progressOpen {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+// EndProgress finishes a progress line left open by a loop that stopped early, so the
+// next message starts on its own line.
+func EndProgress() {
+	if progressOpen {
+		fmt.Fprintln(os.Stderr)
+		progressOpen = false
+	}
+}
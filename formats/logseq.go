@@ -0,0 +1,137 @@
+package formats
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// LogseqFormat writes one outliner page per book into a Logseq graph's pages folder:
+// page properties (title, author), then every highlight as a top-level "- > quote"
+// bullet carrying author, date and chapter as block properties, with its note as a
+// child bullet.
+type LogseqFormat struct {
+	Dir     string
+	written []Output
+}
+
+func (l *LogseqFormat) Name() string { return "logseq" }
+
+// Outputs lists the pages written so far.
+func (l *LogseqFormat) Outputs() []Output { return l.written }
+
+func (l *LogseqFormat) Export(books []Book) error {
+	if err := os.MkdirAll(l.Dir, 0o755); err != nil {
+		return fmt.Errorf("create dir: %w", err)
+	}
+	used := map[string]bool{}
+	for _, b := range books {
+		title := logseqPageTitle(b, used)
+		var page strings.Builder
+		fmt.Fprintf(&page, "title:: %s\n", title)
+		if b.Author != "" {
+			fmt.Fprintf(&page, "author:: [[%s]]\n", logseqLine(b.Author))
+		}
+		page.WriteString("\n")
+		for _, h := range b.Highlights {
+			writeLogseqBlock(&page, b, h)
+		}
+		path := filepath.Join(l.Dir, logseqFileName(title)+".md")
+		if err := os.WriteFile(path, []byte(page.String()), 0o644); err != nil {
+			return fmt.Errorf("write file %s: %w", path, err)
+		}
+		l.written = append(l.written, Output{Path: path})
+	}
+	return nil
+}
+
+// writeLogseqBlock writes one highlight bullet; property lines are indented to the
+// bullet's content and the note is a tab-indented child, as Logseq itself writes them.
+func writeLogseqBlock(w *strings.Builder, b Book, h Highlight) {
+	text := logseqLine(h.Text)
+	if text == "" {
+		return
+	}
+	fmt.Fprintf(w, "- > %s\n", text)
+	if b.Author != "" {
+		fmt.Fprintf(w, "  author:: %s\n", logseqLine(b.Author))
+	}
+	if !h.Time.IsZero() {
+		fmt.Fprintf(w, "  date:: %s\n", h.Time.Format("2006-01-02"))
+	}
+	if h.Chapter != "" {
+		fmt.Fprintf(w, "  chapter:: %s\n", logseqLine(h.Chapter))
+	}
+	if note := logseqLine(h.Note); note != "" {
+		fmt.Fprintf(w, "\t- %s\n", note)
+	}
+}
+
+// logseqLine trims s and joins its lines, since a line break would end the block.
+func logseqLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// logseqPageTitle is the book's page name: its title, or "Title (Author)" when another
+// book already took the title (Logseq page names ignore case), then a -2, -3… suffix.
+func logseqPageTitle(b Book, used map[string]bool) string {
+	base := logseqLine(b.Title)
+	if base == "" {
+		base = "Untitled"
+	}
+	if used[strings.ToLower(base)] && b.Author != "" {
+		base = fmt.Sprintf("%s (%s)", base, logseqLine(b.Author))
+	}
+	title := base
+	for i := 2; used[strings.ToLower(title)]; i++ {
+		title = fmt.Sprintf("%s-%d", base, i)
+	}
+	used[strings.ToLower(title)] = true
+	return title
+}
+
+// logseqFileName encodes a page title the way Logseq's default ("triple-lowbar") file
+// name format does: "/" (a namespace separator) becomes "___" and characters file
+// systems reject are percent-encoded, so Logseq maps the file back to the same title.
+func logseqFileName(title string) string {
+	var sb strings.Builder
+	for _, r := range title {
+		switch r {
+		case '/':
+			sb.WriteString("___")
+		case '<', '>', ':', '"', '\\', '|', '?', '*', '#', '%':
+			fmt.Fprintf(&sb, "%%%02X", r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	name := sb.String()
+	if strings.HasPrefix(name, ".") {
+		name = "%2E" + name[1:] // a leading dot would hide the file
+	}
+	return name
+}
+
+// registration
+type logseqDirFlag struct{}
+
+func (logseqDirFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "logseq-dir", Usage: "Logseq graph pages folder for one page per book (required when --format logseq)"}
+}
+
+func init() {
+	RegisterFormat(&FormatFactory{
+		Name:  "logseq",
+		Flags: []FlagProvider{logseqDirFlag{}},
+		Build: func(r FlagValueResolver) (Format, error) {
+			dir := strings.TrimSpace(r.String("logseq-dir"))
+			if dir == "" {
+				return nil, fmt.Errorf("--logseq-dir required for format logseq")
+			}
+			return &LogseqFormat{Dir: dir}, nil
+		},
+	})
+}
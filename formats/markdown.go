@@ -1,20 +1,85 @@
 package formats
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/urfave/cli/v2"
 )
 
-// MarkdownFormat writes one markdown file per book.
-type MarkdownFormat struct{ Dir string }
+// MarkdownFormat writes one markdown file per book, or all books to SingleFile.
+type MarkdownFormat struct {
+	Dir        string
+	SingleFile string // when set, every book goes into this one file ("-" for stdout) and Dir is ignored
+	// Wikilinks renders the author in headings as an Obsidian [[link]]; WikilinkTitles
+	// does the same for the title.
+	Wikilinks      bool
+	WikilinkTitles bool
+	// SplitChapters writes one file per chapter in a per-book directory plus an index file.
+	SplitChapters bool
+	// Chapters puts a ## heading above each chapter's highlights.
+	Chapters bool
+	// Colors starts each quote with an emoji of its highlight color.
+	Colors bool
+	// IncludeDates follows each quote with its date as an italic *YYYY-MM-DD* line.
+	IncludeDates bool
+	// PreserveNewlines keeps line breaks inside highlights and notes as hard breaks
+	// instead of joining the lines with spaces.
+	PreserveNewlines bool
+	// Frontmatter starts each book file with a YAML block (title, author, book metadata
+	// when known, highlights, exported).
+	Frontmatter bool
+	// ByAuthor puts each book file in a sanitized per-author subdirectory of Dir
+	// ("Unknown" for books without an author) and drops the author from the file name.
+	ByAuthor bool
+	// Index writes a README.md in Dir linking every book file, grouped by author.
+	Index bool
+	// Merge keeps existing book files: only highlights whose quote line is not in the file
+	// yet are appended, under a "## New highlights (date)" heading.
+	Merge      bool
+	appendMode bool
+	streamed   bool // something was already written to stdout
+	written    []Output
+	names      map[string]string     // book key -> file name assigned in this process
+	usedNames  map[string]bool       // lower-cased file names already assigned
+	indexed    map[string]indexEntry // file name -> book listed in the index
+}
+
+// indexEntry is one book line of the README index.
+type indexEntry struct {
+	title, author string
+	highlights    int
+}
+
+// markdownIndexFile is the index written with Index; no book file may take its name.
+const markdownIndexFile = "README"
 
 func (m *MarkdownFormat) Name() string { return "markdown" }
 
+// WritesStdout reports whether the notebook goes to stdout.
+func (m *MarkdownFormat) WritesStdout() bool { return m.SingleFile == "-" }
+
+// Outputs lists the files written so far.
+func (m *MarkdownFormat) Outputs() []Output { return m.written }
+
+// EnableAppend makes later exports append quotes to existing book files.
+func (m *MarkdownFormat) EnableAppend() { m.appendMode = true }
+
 func (m *MarkdownFormat) Export(books []Book) error {
+	if m.SingleFile != "" {
+		return m.exportSingleFile(books)
+	}
 	if m.Dir == "" {
 		return fmt.Errorf("markdown format: empty directory")
 	}
@@ -22,34 +87,559 @@ func (m *MarkdownFormat) Export(books []Book) error {
 		return fmt.Errorf("create dir: %w", err)
 	}
 	for _, b := range books {
-		filename := sanitizeFilename(b.Title)
-		if b.Author != "" {
-			filename = sanitizeFilename(b.Title + "-" + b.Author)
+		filename := m.bookFilename(b)
+		if m.Index {
+			m.addIndexEntry(filename, b)
+		}
+		if m.ByAuthor {
+			if err := os.MkdirAll(filepath.Join(m.Dir, filepath.Dir(filename)), 0o755); err != nil {
+				return fmt.Errorf("create dir: %w", err)
+			}
+		}
+		if m.SplitChapters {
+			if m.appendMode {
+				return fmt.Errorf("markdown format: cannot append to split chapter files")
+			}
+			if err := m.writeChapterFiles(b, filename); err != nil {
+				return err
+			}
+			continue
 		}
 		path := filepath.Join(m.Dir, filename+".md")
-		f, err := os.Create(path)
+		if m.Merge && !m.appendMode {
+			merged, err := m.mergeBookFile(path, b)
+			if err != nil {
+				return err
+			}
+			if merged {
+				continue
+			}
+		}
+		_, statErr := os.Stat(path)
+		f, err := createText(path, m.appendMode)
 		if err != nil {
 			return fmt.Errorf("create file %s: %w", path, err)
 		}
-		if b.Author != "" {
-			fmt.Fprintf(f, "# %s (%s)\n\n", b.Title, b.Author)
-		} else {
-			fmt.Fprintf(f, "# %s\n\n", b.Title)
+		if !m.appendMode || statErr != nil {
+			m.writeFrontmatter(f, b)
+			fmt.Fprintf(f, "# %s\n\n", m.heading(b))
 		}
-		for _, h := range b.Highlights {
-			text := strings.TrimSpace(h.Text)
-			if text == "" {
-				continue
+		m.writeHighlights(f, b.Highlights)
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("close file %s: %w", path, err)
+		}
+		m.written = append(m.written, Output{Path: path})
+	}
+	if m.Index {
+		return m.writeIndex()
+	}
+	return nil
+}
+
+// addIndexEntry records a book for the index; with appends (watch mode) the counts of
+// one run's exports add up.
+func (m *MarkdownFormat) addIndexEntry(filename string, b Book) {
+	if m.indexed == nil {
+		m.indexed = map[string]indexEntry{}
+	}
+	e := indexEntry{title: b.Title, author: b.Author, highlights: len(b.Highlights)}
+	if m.appendMode {
+		e.highlights += m.indexed[filename].highlights
+	}
+	m.indexed[filename] = e
+}
+
+// writeIndex writes Dir/README.md: a section per author (alphabetical, books without an
+// author last) listing that author's books as relative links to their files.
+func (m *MarkdownFormat) writeIndex() error {
+	byAuthor := map[string][]string{}
+	var authors []string
+	for filename, e := range m.indexed {
+		if _, ok := byAuthor[e.author]; !ok {
+			authors = append(authors, e.author)
+		}
+		byAuthor[e.author] = append(byAuthor[e.author], filename)
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		if (authors[i] == "") != (authors[j] == "") {
+			return authors[j] == ""
+		}
+		return strings.ToLower(authors[i]) < strings.ToLower(authors[j])
+	})
+	var sb strings.Builder
+	sb.WriteString("# Highlights\n")
+	for _, a := range authors {
+		name := a
+		if name == "" {
+			name = "Unknown author"
+		}
+		fmt.Fprintf(&sb, "\n## %s\n\n", name)
+		files := byAuthor[a]
+		sort.Slice(files, func(i, j int) bool {
+			return strings.ToLower(m.indexed[files[i]].title) < strings.ToLower(m.indexed[files[j]].title)
+		})
+		for _, filename := range files {
+			e := m.indexed[filename]
+			fmt.Fprintf(&sb, "- [%s](%s) (%d)\n", escapeMarkdown(e.title), markdownLink(filename+".md"), e.highlights)
+		}
+	}
+	path := filepath.Join(m.Dir, markdownIndexFile+".md")
+	if err := writeTextFile(path, []byte(sb.String())); err != nil {
+		return fmt.Errorf("write file %s: %w", path, err)
+	}
+	m.written = append(m.written, Output{Path: path})
+	return nil
+}
+
+// markdownLink turns a relative file path into a link target: forward slashes, each
+// segment URL-escaped so parentheses and the like do not end the link.
+func markdownLink(rel string) string {
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// mergeBookFile appends b's highlights missing from the existing file at path under a
+// dated "## New highlights" heading, leaving the rest of the file (and any edits) as is.
+// It reports false when there is no file yet, so the caller writes it from scratch.
+func (m *MarkdownFormat) mergeBookFile(path string, b Book) (bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err == nil {
+		data, err = decodeText(data)
+	}
+	if err != nil {
+		return false, fmt.Errorf("read file %s: %w", path, err)
+	}
+	existing := map[string]bool{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if quote, ok := strings.CutPrefix(strings.TrimSpace(line), ">"); ok {
+			existing[unescapeMarkdown(strings.TrimSpace(quote))] = true
+		}
+	}
+	var fresh []Highlight
+	for _, h := range b.Highlights {
+		if strings.TrimSpace(h.Text) != "" && !m.quoteKnown(h, existing) {
+			fresh = append(fresh, h)
+		}
+	}
+	if len(fresh) == 0 {
+		return true, nil
+	}
+	f, err := createText(path, true)
+	if err != nil {
+		return false, fmt.Errorf("open file %s: %w", path, err)
+	}
+	// Leave one blank line between the existing text and the new heading.
+	if breaks := len(data) - len(bytes.TrimRight(data, "\n")); breaks < 2 {
+		fmt.Fprint(f, strings.Repeat("\n", 2-breaks))
+	}
+	fmt.Fprintf(f, "## New highlights (%s)\n\n", time.Now().Format("2006-01-02"))
+	writeMarkdownQuotes(f, fresh, "", m.quoteOptions())
+	if err := f.Close(); err != nil {
+		return false, fmt.Errorf("close file %s: %w", path, err)
+	}
+	m.written = append(m.written, Output{Path: path})
+	return true, nil
+}
+
+// quoteKnown reports whether h's quote is among the existing quote lines. The file may
+// have been written with or without PreserveNewlines, so both renderings are tried; a
+// multi-line quote is known by its first line.
+func (m *MarkdownFormat) quoteKnown(h Highlight, existing map[string]bool) bool {
+	opts := m.quoteOptions()
+	for _, newlines := range []bool{false, true} {
+		opts.newlines = newlines
+		first, _, _ := strings.Cut(markdownQuote(h, "", opts), "\n")
+		if existing[unescapeMarkdown(strings.TrimSpace(first))] {
+			return true
+		}
+	}
+	return false
+}
+
+// bookFilename returns the sanitized Title[-Author] file name for a book (Author/Title
+// with ByAuthor), relative to Dir and without extension, with a -2, -3… suffix when
+// another book already got that name (titles differing only in stripped characters, or
+// only in case on case-insensitive file systems). A book keeps its name across exports,
+// so appends go to the same file.
+func (m *MarkdownFormat) bookFilename(b Book) string {
+	key := b.Title + "\x00" + b.Author
+	if name, ok := m.names[key]; ok {
+		return name
+	}
+	if m.names == nil {
+		m.names, m.usedNames = map[string]string{}, map[string]bool{}
+		if m.Index {
+			m.usedNames[strings.ToLower(markdownIndexFile)] = true
+		}
+	}
+	base := sanitizeFilename(b.Title)
+	switch {
+	case m.ByAuthor:
+		author := "Unknown"
+		if strings.TrimSpace(b.Author) != "" {
+			author = sanitizeFilename(b.Author)
+		}
+		base = filepath.Join(author, base)
+	case b.Author != "":
+		base = sanitizeFilename(b.Title + "-" + b.Author)
+	}
+	name := base
+	for i := 2; m.usedNames[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	m.names[key] = name
+	m.usedNames[strings.ToLower(name)] = true
+	return name
+}
+
+// exportSingleFile writes all books to SingleFile (or stdout), each under its own
+// # heading, separated by --- rules.
+func (m *MarkdownFormat) exportSingleFile(books []Book) error {
+	if m.WritesStdout() {
+		w := bufio.NewWriter(os.Stdout)
+		m.writeNotebook(w, books, m.streamed)
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("write markdown: %w", err)
+		}
+		m.streamed = m.streamed || len(books) > 0
+		return nil
+	}
+	_, statErr := os.Stat(m.SingleFile)
+	appending := m.appendMode && statErr == nil
+	f, err := createText(m.SingleFile, m.appendMode)
+	if err != nil {
+		return fmt.Errorf("create file %s: %w", m.SingleFile, err)
+	}
+	w := bufio.NewWriter(f)
+	m.writeNotebook(w, books, appending)
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("write file %s: %w", m.SingleFile, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close file %s: %w", m.SingleFile, err)
+	}
+	m.written = append(m.written, Output{Path: m.SingleFile})
+	return nil
+}
+
+// writeNotebook writes books one after another; continuing puts a rule before the first
+// one too, for output that already holds books.
+func (m *MarkdownFormat) writeNotebook(w io.Writer, books []Book, continuing bool) {
+	for i, b := range books {
+		if i > 0 || continuing {
+			fmt.Fprint(w, "---\n\n")
+		}
+		fmt.Fprintf(w, "# %s\n\n", m.heading(b))
+		m.writeHighlights(w, b.Highlights)
+	}
+}
+
+// writeFrontmatter writes the YAML frontmatter block for a book file when enabled.
+func (m *MarkdownFormat) writeFrontmatter(w io.Writer, b Book) {
+	if !m.Frontmatter {
+		return
+	}
+	fmt.Fprintln(w, "---")
+	fmt.Fprintf(w, "title: %s\n", yamlString(b.Title))
+	fmt.Fprintf(w, "author: %s\n", yamlString(b.Author))
+	for _, kv := range [][2]string{{"series", b.Series}, {"series_number", b.SeriesNumber}, {"isbn", b.ISBN}, {"publisher", b.Publisher}} {
+		if kv[1] != "" {
+			fmt.Fprintf(w, "%s: %s\n", kv[0], yamlString(kv[1]))
+		}
+	}
+	fmt.Fprintf(w, "highlights: %d\n", len(b.Highlights))
+	fmt.Fprintf(w, "exported: %s\n", time.Now().Format("2006-01-02"))
+	fmt.Fprint(w, "---\n\n")
+}
+
+// writeHighlights writes a book's quotes, under a ## heading per chapter when Chapters
+// is set (highlights without a chapter come without a heading).
+func (m *MarkdownFormat) writeHighlights(w io.Writer, highlights []Highlight) {
+	if !m.Chapters {
+		writeMarkdownQuotes(w, highlights, "", m.quoteOptions())
+		return
+	}
+	for _, g := range groupByChapter(highlights) {
+		if g.Title != "" {
+			fmt.Fprintf(w, "## %s\n\n", escapeMarkdown(g.Title))
+		}
+		writeMarkdownQuotes(w, g.Highlights, "", m.quoteOptions())
+	}
+}
+
+// quoteOptions are the optional extras of writeMarkdownQuotes.
+type quoteOptions struct {
+	dates    bool // follow each quote with an italic *YYYY-MM-DD* line when its date was parsed
+	colors   bool // start each quote with its highlight color emoji
+	newlines bool // keep line breaks inside quotes and notes
+}
+
+func (m *MarkdownFormat) quoteOptions() quoteOptions {
+	return quoteOptions{dates: m.IncludeDates, colors: m.Colors, newlines: m.PreserveNewlines}
+}
+
+// writeMarkdownQuotes writes each non-empty highlight as a blockquote paragraph,
+// followed by its note, if any, as a plain paragraph. A non-empty suffix (e.g. a tag)
+// is appended to every quote line.
+func writeMarkdownQuotes(w io.Writer, highlights []Highlight, suffix string, opts quoteOptions) {
+	for _, h := range highlights {
+		text := markdownQuote(h, suffix, opts)
+		if text == "" {
+			continue
+		}
+		fmt.Fprintf(w, "> %s\n\n", text)
+		if opts.dates && !h.Time.IsZero() {
+			fmt.Fprintf(w, "*%s*\n\n", h.Time.Format("2006-01-02"))
+		}
+		if h.Note != "" {
+			note := strings.ReplaceAll(h.Note, "\n", " ")
+			if opts.newlines {
+				note = strings.Join(strings.FieldsFunc(strings.TrimSpace(h.Note), func(r rune) bool { return r == '\n' || r == '\r' }), "  \n")
+			}
+			fmt.Fprintf(w, "**Note:** %s\n\n", note)
+		}
+	}
+}
+
+// markdownQuote renders the text of a highlight's quote line (without the "> " marker);
+// "" for an empty highlight.
+func markdownQuote(h Highlight, suffix string, opts quoteOptions) string {
+	text := strings.TrimSpace(h.Text)
+	if text == "" {
+		return ""
+	}
+	if len(h.Runs) > 0 {
+		text = strings.TrimSpace(markdownRuns(h.Runs))
+	} else {
+		text = escapeMarkdown(text)
+	}
+	if opts.newlines {
+		text = markdownQuoteLines(text)
+	} else {
+		text = escapeBlockStart(strings.ReplaceAll(text, "\n", " "))
+	}
+	if suffix != "" {
+		text += " " + suffix
+	}
+	if e := colorEmoji[h.Color]; opts.colors && e != "" {
+		text = e + " " + text
+	}
+	return text
+}
+
+// markdownQuoteLines keeps the line breaks of a quote's text: lines end in a two-space
+// hard break and continue the blockquote, and blank lines (collapsed to one) become an
+// empty ">" line, so stanzas stay apart.
+func markdownQuoteLines(text string) string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		last := len(lines) - 1
+		if line == "" {
+			if last >= 0 && lines[last] != "" {
+				lines = append(lines, "")
 			}
-			fmt.Fprintf(f, "> %s\n\n", strings.ReplaceAll(text, "\n", " "))
+			continue
+		}
+		if last >= 0 && lines[last] != "" {
+			lines[last] += "  "
+		}
+		lines = append(lines, escapeBlockStart(line))
+	}
+	var sb strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			sb.WriteString("\n>")
+			if line != "" {
+				sb.WriteString(" ")
+			}
+		}
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
+
+// chapterGroup is a run of a book's highlights sharing one chapter, in reading order.
+type chapterGroup struct {
+	Title      string
+	Highlights []Highlight
+}
+
+// groupByChapter groups highlights by chapter in order of first appearance; highlights
+// without a resolved chapter share one group.
+func groupByChapter(highlights []Highlight) []chapterGroup {
+	var groups []chapterGroup
+	index := map[string]int{}
+	for _, h := range highlights {
+		i, ok := index[h.Chapter]
+		if !ok {
+			i = len(groups)
+			index[h.Chapter] = i
+			groups = append(groups, chapterGroup{Title: h.Chapter})
+		}
+		groups[i].Highlights = append(groups[i].Highlights, h)
+	}
+	return groups
+}
+
+// writeChapterFiles writes one file per chapter under Dir/<filename>/ and an index
+// file Dir/<filename>.md linking them (relative to the index) in reading order.
+func (m *MarkdownFormat) writeChapterFiles(b Book, filename string) error {
+	bookDir := filepath.Join(m.Dir, filename)
+	if err := os.MkdirAll(bookDir, 0o755); err != nil {
+		return fmt.Errorf("create dir: %w", err)
+	}
+	groups := groupByChapter(b.Highlights)
+	width := len(strconv.Itoa(len(groups)))
+	if width < 2 {
+		width = 2
+	}
+	var index strings.Builder
+	m.writeFrontmatter(&index, b)
+	fmt.Fprintf(&index, "# %s\n\n", m.heading(b))
+	for i, g := range groups {
+		title := g.Title
+		if title == "" {
+			title = "Other highlights"
 		}
+		name := fmt.Sprintf("%0*d-%s.md", width, i+1, sanitizeFilename(title))
+		path := filepath.Join(bookDir, name)
+		f, err := createText(path, false)
+		if err != nil {
+			return fmt.Errorf("create file %s: %w", path, err)
+		}
+		fmt.Fprintf(f, "# %s\n\n", escapeMarkdown(title))
+		writeMarkdownQuotes(f, g.Highlights, "", m.quoteOptions())
 		if err := f.Close(); err != nil {
 			return fmt.Errorf("close file %s: %w", path, err)
 		}
+		m.written = append(m.written, Output{Path: path})
+		fmt.Fprintf(&index, "- [%s](%s) (%d)\n", escapeMarkdown(title), markdownLink(filepath.Join(filepath.Base(filename), name)), len(g.Highlights))
+	}
+	path := filepath.Join(m.Dir, filename+".md")
+	if err := writeTextFile(path, []byte(index.String())); err != nil {
+		return fmt.Errorf("write file %s: %w", path, err)
 	}
+	m.written = append(m.written, Output{Path: path})
 	return nil
 }
 
+// heading renders the H1 text, linking author/title when wikilinks are enabled
+// (names outside links are escaped).
+func (m *MarkdownFormat) heading(b Book) string {
+	title, author := escapeMarkdown(b.Title), escapeMarkdown(b.Author)
+	if m.WikilinkTitles {
+		title = wikilink(b.Title)
+	}
+	if author != "" && m.Wikilinks {
+		author = wikilink(b.Author)
+	}
+	if author == "" {
+		return title
+	}
+	return fmt.Sprintf("%s (%s)", title, author)
+}
+
+// markdownRuns renders emphasis runs with */** markers, keeping surrounding
+// whitespace outside the markers so they stay valid markdown.
+func markdownRuns(runs []TextRun) string {
+	var sb strings.Builder
+	for _, r := range runs {
+		marker := ""
+		switch {
+		case r.Bold && r.Italic:
+			marker = "***"
+		case r.Bold:
+			marker = "**"
+		case r.Italic:
+			marker = "*"
+		}
+		core := strings.TrimSpace(r.Text)
+		if marker == "" || core == "" {
+			sb.WriteString(escapeMarkdown(r.Text))
+			continue
+		}
+		lead := r.Text[:strings.Index(r.Text, core)]
+		trail := r.Text[len(lead)+len(core):]
+		sb.WriteString(lead + marker + escapeMarkdown(core) + marker + trail)
+	}
+	return sb.String()
+}
+
+// markdownEscaper backslash-escapes the characters that would start emphasis, code,
+// links, headings or HTML in text taken from the book.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	"*", `\*`,
+	"_", `\_`,
+	"[", `\[`,
+	"]", `\]`,
+	"#", `\#`,
+	"<", `\<`,
+)
+
+// escapeMarkdown makes s render literally in markdown.
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// unescapeMarkdown drops the backslash before escaped punctuation, so quotes written
+// before escaping was added (or with fewer escapes) compare equal.
+func unescapeMarkdown(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", s[i+1]) >= 0 {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// escapeBlockStart escapes a leading list or blockquote marker ("- ", "+ ", ">", "1. "),
+// which would otherwise nest a list or quote inside the quote line.
+func escapeBlockStart(s string) string {
+	if strings.HasPrefix(s, ">") || strings.HasPrefix(s, "- ") || strings.HasPrefix(s, "+ ") {
+		return `\` + s
+	}
+	digits := len(s) - len(strings.TrimLeft(s, "0123456789"))
+	if digits > 0 && digits <= 9 && len(s) > digits && (s[digits] == '.' || s[digits] == ')') {
+		return s[:digits] + `\` + s[digits:]
+	}
+	return s
+}
+
+// wikilink wraps a name as an Obsidian link; the target drops characters Obsidian
+// does not allow in link targets (#|^[]:\/) and collapses whitespace. A name with
+// nothing left to link to is returned escaped, as plain text.
+func wikilink(name string) string {
+	target := strings.Map(func(r rune) rune {
+		switch r {
+		case '#', '|', '^', '[', ']', ':', '\\', '/':
+			return ' '
+		}
+		return r
+	}, name)
+	target = strings.Join(strings.Fields(target), " ")
+	if target == "" {
+		return escapeMarkdown(name)
+	}
+	return "[[" + target + "]]"
+}
+
+// sanitizeFilename turns s into a file name: characters file systems reject are dropped
+// or become dashes and whitespace becomes a dash.
 func sanitizeFilename(s string) string {
 	s = strings.TrimSpace(s)
 	replacer := strings.NewReplacer(
@@ -75,19 +665,108 @@ func sanitizeFilename(s string) string {
 type markdownDirFlag struct{}
 
 func (markdownDirFlag) CLIFlag() any {
-	return &cli.StringFlag{Name: "markdown-dir", Usage: "Directory for markdown output (required when --format markdown)"}
+	return &cli.StringFlag{Name: "markdown-dir", Usage: "Directory for markdown output, or - to write all books to stdout (required when --format markdown)"}
+}
+
+type markdownWikilinksFlag struct{}
+
+func (markdownWikilinksFlag) CLIFlag() any {
+	return &cli.BoolFlag{Name: "markdown-wikilinks", Usage: "Render the author in headings as an Obsidian [[wikilink]]"}
+}
+
+type markdownWikilinkTitlesFlag struct{}
+
+func (markdownWikilinkTitlesFlag) CLIFlag() any {
+	return &cli.BoolFlag{Name: "markdown-wikilinks-titles", Usage: "Render the book title in headings as an Obsidian [[wikilink]]"}
+}
+
+type markdownFrontmatterFlag struct{}
+
+func (markdownFrontmatterFlag) CLIFlag() any {
+	return &cli.BoolFlag{Name: "markdown-frontmatter", Usage: "Start each book file with YAML frontmatter (title, author, highlight count, export date)"}
+}
+
+type markdownSingleFileFlag struct{}
+
+func (markdownSingleFileFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "markdown-single-file", Usage: "Write all books into this one markdown file (- for stdout) instead of one file per book in --markdown-dir"}
+}
+
+type markdownChaptersFlag struct{}
+
+func (markdownChaptersFlag) CLIFlag() any {
+	return &cli.BoolFlag{Name: "markdown-chapters", Usage: "Group highlights under a ## heading per chapter"}
+}
+
+type markdownIncludeDatesFlag struct{}
+
+func (markdownIncludeDatesFlag) CLIFlag() any {
+	return &cli.BoolFlag{Name: "markdown-include-dates", Usage: "Follow each quote with the highlight date as an italic YYYY-MM-DD line"}
+}
+
+type markdownPreserveNewlinesFlag struct{}
+
+func (markdownPreserveNewlinesFlag) CLIFlag() any {
+	return &cli.BoolFlag{Name: "preserve-newlines", Usage: "Keep line breaks inside highlights and notes, e.g. for poetry or code (markdown; Notion always keeps them)"}
+}
+
+type markdownColorsFlag struct{}
+
+func (markdownColorsFlag) CLIFlag() any {
+	return &cli.BoolFlag{Name: "markdown-colors", Usage: "Start each quote with an emoji of its highlight color"}
+}
+
+type markdownAppendFlag struct{}
+
+func (markdownAppendFlag) CLIFlag() any {
+	return &cli.BoolFlag{Name: "markdown-append", Usage: "Keep existing book files and append only new highlights under a dated \"## New highlights\" heading"}
+}
+
+type markdownByAuthorFlag struct{}
+
+func (markdownByAuthorFlag) CLIFlag() any {
+	return &cli.BoolFlag{Name: "markdown-by-author", Usage: "Put book files in a subfolder per author (Dir/Author/Title.md; Unknown for no author)"}
+}
+
+type markdownIndexFlag struct{}
+
+func (markdownIndexFlag) CLIFlag() any {
+	return &cli.BoolFlag{Name: "markdown-index", Usage: "Also write README.md in --markdown-dir linking every book file, grouped by author"}
+}
+
+type markdownSplitChaptersFlag struct{}
+
+func (markdownSplitChaptersFlag) CLIFlag() any {
+	return &cli.BoolFlag{Name: "markdown-split-chapters", Usage: "Write one file per chapter under a per-book directory, plus an index file per book"}
 }
 
 func init() {
 	RegisterFormat(&FormatFactory{
 		Name:  "markdown",
-		Flags: []FlagProvider{markdownDirFlag{}},
+		Flags: []FlagProvider{markdownDirFlag{}, markdownWikilinksFlag{}, markdownWikilinkTitlesFlag{}, markdownChaptersFlag{}, markdownIncludeDatesFlag{}, markdownColorsFlag{}, markdownSplitChaptersFlag{}, markdownSingleFileFlag{}, markdownFrontmatterFlag{}, markdownAppendFlag{}, markdownByAuthorFlag{}, markdownIndexFlag{}, markdownPreserveNewlinesFlag{}},
 		Build: func(r FlagValueResolver) (Format, error) {
 			dir := strings.TrimSpace(r.String("markdown-dir"))
-			if dir == "" {
-				return nil, fmt.Errorf("--markdown-dir required for format markdown")
+			single := strings.TrimSpace(r.String("markdown-single-file"))
+			if dir == "" && single == "" {
+				return nil, fmt.Errorf("--markdown-dir or --markdown-single-file required for format markdown")
+			}
+			if dir == "-" {
+				// Per-book files make no sense on stdout: stream the single-file notebook.
+				dir, single = "", "-"
+			}
+			split := r.Bool("markdown-split-chapters")
+			if single != "" && split {
+				return nil, fmt.Errorf("--markdown-single-file and --markdown-split-chapters are mutually exclusive")
+			}
+			merge := r.Bool("markdown-append")
+			if merge && (single != "" || split) {
+				return nil, fmt.Errorf("--markdown-append only works with per-book files (not --markdown-single-file or --markdown-split-chapters)")
+			}
+			index := r.Bool("markdown-index")
+			if index && single != "" {
+				return nil, fmt.Errorf("--markdown-index needs --markdown-dir (not --markdown-single-file)")
 			}
-			return &MarkdownFormat{Dir: dir}, nil
+			return &MarkdownFormat{Dir: dir, SingleFile: single, Wikilinks: r.Bool("markdown-wikilinks"), WikilinkTitles: r.Bool("markdown-wikilinks-titles"), SplitChapters: split, Chapters: r.Bool("markdown-chapters"), IncludeDates: r.Bool("markdown-include-dates"), Colors: r.Bool("markdown-colors"), Frontmatter: r.Bool("markdown-frontmatter"), Merge: merge, ByAuthor: r.Bool("markdown-by-author"), Index: index, PreserveNewlines: r.Bool("preserve-newlines")}, nil
 		},
 	})
 }
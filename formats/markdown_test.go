@@ -0,0 +1,109 @@
+package formats
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMarkdownCollidingTitlesGetSeparateFiles(t *testing.T) {
+	dir := t.TempDir()
+	m := &MarkdownFormat{Dir: dir}
+	books := []Book{
+		{Title: "Book One?", Highlights: []Highlight{{Text: "from the question edition"}}},
+		{Title: "Book One", Highlights: []Highlight{{Text: "from the plain edition"}}},
+	}
+	if err := m.Export(books); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"Book-One.md":   "> from the question edition",
+		"Book-One-2.md": "> from the plain edition",
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("missing %s: %v", name, err)
+		}
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s does not hold its own book:\n%s", name, data)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("wrote %d files, want 2", len(entries))
+	}
+}
+
+func TestMarkdownEscapesSpecialCharacters(t *testing.T) {
+	dir := t.TempDir()
+	m := &MarkdownFormat{Dir: dir}
+	b := Book{Title: "C* and _pointers_", Author: "K&R `classic`", Highlights: []Highlight{
+		{Text: "# not a heading, use `*p` and a_b"},
+		{Text: "1. not a list item"},
+	}}
+	if err := m.Export([]Book{b}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, m.bookFilename(b)+".md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# C\\* and \\_pointers\\_ (K&R \\`classic\\`)\n\n" +
+		"> \\# not a heading, use \\`\\*p\\` and a\\_b\n\n" +
+		"> 1\\. not a list item\n\n"
+	if string(data) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestMarkdownSplitChapterIndexLinks(t *testing.T) {
+	dir := t.TempDir()
+	m := &MarkdownFormat{Dir: dir, SplitChapters: true, Chapters: true}
+	b := Book{Title: "Dune (Part 1)", Highlights: []Highlight{
+		{Text: "I must not fear.", Chapter: "Chapter 1 (Fear)"},
+		{Text: "Fear is the mind-killer.", Chapter: "100% Spice"},
+	}}
+	if err := m.Export([]Book{b}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "Dune-(Part-1).md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, link := range []string{
+		"- [Chapter 1 (Fear)](Dune-%28Part-1%29/01-Chapter-1-%28Fear%29.md) (1)\n",
+		"- [100% Spice](Dune-%28Part-1%29/02-100%25-Spice.md) (1)\n",
+	} {
+		if !strings.Contains(string(data), link) {
+			t.Errorf("index lacks %q:\n%s", link, data)
+		}
+	}
+	for _, name := range []string{"01-Chapter-1-(Fear).md", "02-100%-Spice.md"} {
+		if _, err := os.Stat(filepath.Join(dir, "Dune-(Part-1)", name)); err != nil {
+			t.Errorf("link target missing: %v", err)
+		}
+	}
+}
+
+func TestMarkdownWikilinkHeadings(t *testing.T) {
+	b := Book{Title: "Dune: Messiah", Author: "Frank Herbert"}
+	tests := []struct {
+		name string
+		m    MarkdownFormat
+		b    Book
+		want string
+	}{
+		{"off", MarkdownFormat{}, b, `Dune: Messiah (Frank Herbert)`},
+		{"author", MarkdownFormat{Wikilinks: true}, b, `Dune: Messiah ([[Frank Herbert]])`},
+		{"title", MarkdownFormat{WikilinkTitles: true}, b, `[[Dune Messiah]] (Frank Herbert)`},
+		{"both", MarkdownFormat{Wikilinks: true, WikilinkTitles: true}, b, `[[Dune Messiah]] ([[Frank Herbert]])`},
+		{"nothing left to link", MarkdownFormat{Wikilinks: true, WikilinkTitles: true}, Book{Title: "Dune", Author: "[#]"}, `[[Dune]] (\[\#\])`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.heading(tt.b); got != tt.want {
+				t.Errorf("heading = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
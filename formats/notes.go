@@ -0,0 +1,25 @@
+package formats
+
+// DefaultNoteSeparator joins a quoted highlight and its note when notes are inlined.
+const DefaultNoteSeparator = " — Note: "
+
+// InlineNotes folds each highlight's note into its text as `"<text>"<sep><note>` and
+// clears Note, so formats without a place for notes still carry the commentary.
+// Highlights without a note are left untouched.
+func InlineNotes(books []Book, sep string) []Book {
+	out := make([]Book, 0, len(books))
+	for _, b := range books {
+		hs := make([]Highlight, len(b.Highlights))
+		for i, h := range b.Highlights {
+			if h.Note != "" {
+				h.Text = `"` + h.Text + `"` + sep + h.Note
+				h.Note = ""
+				h.Runs = nil // emphasis offsets no longer match the combined text
+			}
+			hs[i] = h
+		}
+		b.Highlights = hs
+		out = append(out, b)
+	}
+	return out
+}
@@ -1,185 +1,995 @@
 package formats
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf16"
 
 	"github.com/urfave/cli/v2"
 )
 
-// NotionClient is a minimal client for creating pages in a database.
+// notionAPI is the Notion API base URL.
+var notionAPI = "https://api.notion.com/v1"
+
+const notionVersion = "2022-06-28"
+
+// NotionClient is a minimal client for creating pages in a database, or as sub-pages of
+// a plain page when ParentPage is set. Only schema loading is safe for concurrent use:
+// the page caches and the list of created pages are not locked, so pages are written
+// from one goroutine.
 type NotionClient struct {
 	httpClient    *http.Client
 	token         string
 	databaseID    string
 	titlePropName string
-	resolvedTitle bool
+	schema        map[string]string // property name -> Notion property type
+	schemaOnce    sync.Once         // loads schema and titlePropName exactly once, even when first needed by several goroutines
+	schemaErr     error
+	// Replace rewrites existing pages: their blocks are deleted and the current
+	// highlights appended, instead of appending only the missing ones.
+	Replace bool
+	// ParentPage, when set, makes book and author pages child pages of this page instead
+	// of database rows. Such pages only have a title, so no other property is written.
+	ParentPage string
+	// Strict fails page creation when a property we write is missing from the
+	// database schema or has a different type, instead of retrying without it.
+	Strict bool
+	// CacheAll lists the whole database once and answers existence checks from
+	// memory; databases with more than CacheLimit pages fall back to per-title queries.
+	CacheAll   bool
+	CacheLimit int
+	pageCache  map[string]string // page title -> page ID
+	cacheState int               // cacheUnloaded, cacheReady or cacheTooLarge
+	lookups    map[string]string // per-title query results of this run ("" = not found)
+	// URLProperty receives the book's store URL (url or rich_text property); "" disables.
+	URLProperty string
+	// Covers, when set, supplies an external cover image for newly created book pages.
+	Covers *CoverLookup
+	// MetadataCovers uses the cover URL from the book's metadata for new book pages,
+	// before (or instead of) the Covers lookup.
+	MetadataCovers bool
+	created        []Output // pages created in this run
+	// MaxRetries is how often a rate-limited (429) request is retried, waiting for
+	// Retry-After or, without it, an exponential backoff starting at one second.
+	MaxRetries int
+	// Chapters puts a heading above each chapter's highlights (heading_2 on book
+	// pages, heading_3 under the book headings of author pages).
+	Chapters bool
+	// BlockType is the block each highlight becomes: quote (default), callout or paragraph.
+	BlockType   string
+	CalloutIcon string // emoji icon of callout blocks
+	// Colors gives each highlight block the background of its highlight color.
+	Colors bool
+	// IncludeDates adds a gray caption with the highlight's date after each highlight block.
+	IncludeDates bool
+	ctx          context.Context // requests of the running export; nil = context.Background
 }
 
+const (
+	cacheUnloaded = iota
+	cacheReady
+	cacheTooLarge
+)
+
 func NewNotionClient(token, databaseID string) *NotionClient {
 	return &NotionClient{httpClient: &http.Client{Timeout: 15 * time.Second}, token: token, databaseID: databaseID, titlePropName: "Title"}
 }
 
+// Notion page grouping modes.
+const (
+	notionGroupBook   = "book"
+	notionGroupAuthor = "author"
+)
+
+// Notion sync modes for existing pages.
+const (
+	notionModeAppend  = "append"
+	notionModeReplace = "replace"
+)
+
+// Notion block types a highlight can be rendered as.
+const (
+	notionBlockQuote     = "quote"
+	notionBlockCallout   = "callout"
+	notionBlockParagraph = "paragraph"
+)
+
+// seriesRelationProp is the self-relation property linking a volume to the next one.
+const seriesRelationProp = "Next in Series"
+
 // NotionFormat implements Format using an underlying NotionClient.
-type NotionFormat struct{ Client *NotionClient }
+type NotionFormat struct {
+	Client  *NotionClient
+	GroupBy string // "book" (default) or "author"
+	// SeriesRelations links each series volume to the next via the "Next in Series"
+	// self-relation once all pages exist (book grouping only).
+	SeriesRelations bool
+	// Preflight verifies database access and schema before any page is created.
+	Preflight bool
+	// MaxNew asks for confirmation (or Yes) before creating more than this many
+	// new pages in one run – a guard against pointing at the wrong database. 0 disables.
+	MaxNew int
+	Yes    bool
+	// FailFast stops at the first page that fails; by default the others are still
+	// synced and the failures are reported together at the end.
+	FailFast bool
+}
 
 func (n *NotionFormat) Name() string { return "notion" }
 
+// EnableAppend is a no-op: every export already appends only the highlights an
+// existing page lacks.
+func (n *NotionFormat) EnableAppend() {}
+
+// Outputs lists the URLs of pages created so far.
+func (n *NotionFormat) Outputs() []Output {
+	if n.Client == nil {
+		return nil
+	}
+	return n.Client.created
+}
+
 func (n *NotionFormat) Export(books []Book) error {
+	return n.ExportContext(context.Background(), books)
+}
+
+// ExportContext syncs books like Export. Once ctx is done no new page is started; a page
+// already being written is finished first, so an interrupted sync never leaves one half
+// written and a re-run picks up with the next page.
+func (n *NotionFormat) ExportContext(ctx context.Context, books []Book) error {
 	if n.Client == nil {
 		return fmt.Errorf("nil Notion client")
 	}
+	n.Client.ctx = ctx
+	defer func() { n.Client.ctx = nil }()
+	if n.Preflight {
+		if err := n.Client.Preflight(); err != nil {
+			return err
+		}
+	}
+	if err := n.guardNewPages(books); err != nil {
+		return err
+	}
+	if n.GroupBy == notionGroupAuthor {
+		return n.exportByAuthor(books)
+	}
+	defer EndProgress()
+	pageIDs := make([]string, len(books))
+	var failed pageFailures
+	for i, b := range books {
+		if err := ctx.Err(); err != nil {
+			return failed.join(fmt.Errorf("notion sync interrupted after %d of %d pages: %w", i, len(books), err))
+		}
+		Progress(i+1, len(books), b.Title)
+		id, err := n.Client.EnsureBookPage(b)
+		if err != nil {
+			if n.FailFast {
+				return fmt.Errorf("notion export '%s': %w", b.Title, err)
+			}
+			failed.add(b.Title, err)
+			continue
+		}
+		pageIDs[i] = id
+	}
+	if n.SeriesRelations {
+		if err := n.Client.linkSeries(books, pageIDs); err != nil {
+			return failed.join(err)
+		}
+	}
+	return failed.summary("books", len(books))
+}
+
+// pageFailures collects the pages that failed to sync, for one report at the end.
+type pageFailures []string
+
+// add records a failed page and logs it right away (warnings show at every log level).
+func (f *pageFailures) add(title string, err error) {
+	EndProgress()
+	log.Printf("notion export '%s' failed: %v", title, err)
+	*f = append(*f, fmt.Sprintf("'%s': %v", title, err))
+}
+
+// summary is the error listing every failure, or nil when none failed.
+func (f pageFailures) summary(what string, total int) error {
+	if len(f) == 0 {
+		return nil
+	}
+	return fmt.Errorf("notion sync failed for %d of %d %s:\n  %s", len(f), total, what, strings.Join(f, "\n  "))
+}
+
+// join returns err, preceded by the failures collected before it.
+func (f pageFailures) join(err error) error {
+	if len(f) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w; earlier failures:\n  %s", err, strings.Join(f, "\n  "))
+}
+
+// guardNewPages counts the pages this run would create and, above MaxNew, requires
+// --yes or an interactive confirmation.
+func (n *NotionFormat) guardNewPages(books []Book) error {
+	if n.MaxNew <= 0 || n.Yes {
+		return nil
+	}
+	titles := map[string]bool{}
 	for _, b := range books {
-		highlights := make([]string, len(b.Highlights))
-		for i, h := range b.Highlights {
-			highlights[i] = h.Text
+		if n.GroupBy == notionGroupAuthor {
+			titles[authorPageTitle(b.Author)] = true
+		} else {
+			titles[bookPageTitle(b.Title, b.Author)] = true
 		}
-		if err := n.Client.EnsureBookPage(b.Title, b.Author, highlights); err != nil {
-			return fmt.Errorf("notion export '%s': %w", b.Title, err)
+	}
+	if len(titles) <= n.MaxNew {
+		return nil
+	}
+	missing := 0
+	for t := range titles {
+		id, err := n.Client.findPageByTitle(t)
+		if err != nil {
+			return fmt.Errorf("check existing page: %w", err)
+		}
+		if id == "" {
+			missing++
 		}
 	}
+	if missing <= n.MaxNew {
+		return nil
+	}
+	msg := fmt.Sprintf("about to create %d new Notion pages (more than --notion-max-new %d)", missing, n.MaxNew)
+	if !confirm(msg + " – is the database ID correct? Continue?") {
+		return fmt.Errorf("%s; re-run with --yes to proceed", msg)
+	}
 	return nil
 }
 
-// EnsureBookPage creates a page for the book (Title + optional Author) and appends highlight blocks.
-func (n *NotionClient) EnsureBookPage(title, author string, highlights []string) error {
+// confirm asks a yes/no question on stderr when stdin is a terminal; otherwise it returns false.
+func confirm(question string) bool {
+	fi, err := os.Stdin.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// exportByAuthor creates one page per author holding all of that author's books.
+func (n *NotionFormat) exportByAuthor(books []Book) error {
+	byAuthor := map[string][]Book{}
+	authors := make([]string, 0)
+	for _, b := range books {
+		if _, ok := byAuthor[b.Author]; !ok {
+			authors = append(authors, b.Author)
+		}
+		byAuthor[b.Author] = append(byAuthor[b.Author], b)
+	}
+	defer EndProgress()
+	var failed pageFailures
+	for i, a := range authors {
+		if err := n.Client.context().Err(); err != nil {
+			return failed.join(fmt.Errorf("notion sync interrupted after %d of %d pages: %w", i, len(authors), err))
+		}
+		Progress(i+1, len(authors), authorPageTitle(a))
+		if _, err := n.Client.EnsureAuthorPage(a, byAuthor[a]); err != nil {
+			if n.FailFast {
+				return fmt.Errorf("notion export author '%s': %w", a, err)
+			}
+			failed.add(authorPageTitle(a), err)
+		}
+	}
+	return failed.summary("authors", len(authors))
+}
+
+// EnsureBookPage creates a page for the book (Title + optional Author, Date of the latest
+// highlight and store URL) and appends highlight blocks. It returns the ID of the new or already existing page.
+func (n *NotionClient) EnsureBookPage(b Book) (string, error) {
 	if n == nil {
+		return "", nil
+	}
+	notionTitle := bookPageTitle(b.Title, b.Author)
+	props := map[string]any{}
+	if n.ParentPage != "" {
+		// Child pages of a page have no properties besides the title.
+		return n.ensurePage(notionTitle, props, n.coverFunc(b), n.bookBlocks(b))
+	}
+	if b.Author != "" {
+		props["Author"] = map[string]any{"rich_text": []map[string]any{{"text": map[string]string{"content": b.Author}}}}
+	}
+	if n.URLProperty != "" && b.StoreURL != "" {
+		props[n.URLProperty] = n.urlPropertyValue(b.StoreURL)
+	}
+	if latest := latestHighlightTime(b.Highlights); !latest.IsZero() {
+		props["Date"] = map[string]any{"date": map[string]string{"start": latest.Format(time.RFC3339)}}
+	}
+	n.addMetadataProps(props, b)
+	for name, v := range map[string]int{"Progress": b.Progress, "Words": b.Words, "Characters": b.Characters} {
+		if n.schema[name] == "number" {
+			props[name] = map[string]any{"number": v}
+		}
+	}
+	return n.ensurePage(notionTitle, props, n.coverFunc(b), n.bookBlocks(b))
+}
+
+// coverFunc returns the cover for a new book page: the book's own cover URL with
+// MetadataCovers, else the Covers lookup (nil when neither applies).
+func (n *NotionClient) coverFunc(b Book) func() string {
+	if n.MetadataCovers && b.CoverURL != "" {
+		return func() string { return b.CoverURL }
+	}
+	if n.Covers == nil {
 		return nil
 	}
-	if !n.resolvedTitle {
-		_ = n.resolveTitlePropertyName()
+	return func() string { return n.Covers.CoverURL(n.context(), b.Title, b.Author, b.ISBN) }
+}
+
+// bookBlocks returns the ensurePage build function of a book page.
+func (n *NotionClient) bookBlocks(b Book) func(existing map[string]bool) []map[string]any {
+	return func(existing map[string]bool) []map[string]any {
+		blocks := n.chapterBlocks(missingHighlights(b.Highlights, existing), "heading_2")
+		if len(existing) > 0 && len(blocks) > 0 { // keep the blank line before the first appended quote
+			blocks = append([]map[string]any{{"object": "block", "type": "paragraph", "paragraph": map[string]any{"rich_text": []map[string]any{}}}}, blocks...)
+		}
+		return blocks
+	}
+}
+
+// metadataProps are the book metadata properties written when the database defines them.
+var metadataProps = []struct {
+	name  string
+	value func(Book) string
+}{
+	{"ISBN", func(b Book) string { return b.ISBN }},
+	{"Publisher", func(b Book) string { return b.Publisher }},
+	{"Series", func(b Book) string { return b.Series }},
+	{"Series Number", func(b Book) string { return b.SeriesNumber }},
+}
+
+// addMetadataProps adds the book's ISBN, publisher and series to props, shaped for each
+// property's type in the schema (rich_text, select or number); a property the database
+// lacks is not written.
+func (n *NotionClient) addMetadataProps(props map[string]any, b Book) {
+	if n.ensureSchema() != nil {
+		return
+	}
+	for _, p := range metadataProps {
+		v := strings.TrimSpace(p.value(b))
+		if v == "" {
+			continue
+		}
+		switch n.schema[p.name] {
+		case "rich_text":
+			props[p.name] = map[string]any{"rich_text": []map[string]any{{"text": map[string]string{"content": v}}}}
+		case "select":
+			props[p.name] = map[string]any{"select": map[string]string{"name": strings.Join(strings.Fields(strings.ReplaceAll(v, ",", " ")), " ")}} // select options cannot contain commas
+		case "number":
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				props[p.name] = map[string]any{"number": f}
+			}
+		}
+	}
+}
+
+// latestHighlightTime returns the most recent parsed highlight date (zero if none parsed).
+func latestHighlightTime(highlights []Highlight) time.Time {
+	var latest time.Time
+	for _, h := range highlights {
+		if h.Time.After(latest) {
+			latest = h.Time
+		}
+	}
+	return latest
+}
+
+// urlPropertyValue shapes a link for URLProperty according to its schema type: url
+// (the default when the schema is unknown) or rich_text.
+func (n *NotionClient) urlPropertyValue(u string) map[string]any {
+	_ = n.ensureSchema()
+	if n.schema[n.URLProperty] == "rich_text" {
+		return map[string]any{"rich_text": []map[string]any{{"text": map[string]any{"content": u, "link": map[string]string{"url": u}}}}}
+	}
+	return map[string]any{"url": u}
+}
+
+// bookPageTitle is the page title used for a book: "Title (Author)" or just "Title".
+func bookPageTitle(title, author string) string {
+	if author == "" {
+		return title
 	}
-	notionTitle := title
-	if author != "" {
-		notionTitle = fmt.Sprintf("%s (%s)", title, author)
+	return fmt.Sprintf("%s (%s)", title, author)
+}
+
+// authorPageTitle is the page title used for an author in author grouping.
+func authorPageTitle(author string) string {
+	if author == "" {
+		return "Unknown author"
+	}
+	return author
+}
+
+// EnsureAuthorPage creates a page for an author with a heading_2 section per book.
+func (n *NotionClient) EnsureAuthorPage(author string, books []Book) (string, error) {
+	if n == nil {
+		return "", nil
 	}
-	exists, err := n.pageExistsByTitle(notionTitle)
+	title := authorPageTitle(author)
+	return n.ensurePage(title, map[string]any{}, nil, func(existing map[string]bool) []map[string]any {
+		var blocks []map[string]any
+		for _, b := range books {
+			missing := missingHighlights(b.Highlights, existing)
+			if len(missing) == 0 {
+				continue
+			}
+			blocks = append(blocks, headingBlock("heading_2", b.Title))
+			blocks = append(blocks, n.chapterBlocks(missing, "heading_3")...)
+		}
+		return blocks
+	})
+}
+
+// ensurePage creates a page titled title (unless one already exists) and appends the
+// blocks returned by build. build receives the quote texts already on an existing page
+// (nil for a new page) so only missing highlights are appended.
+// props holds optional properties besides the title; unless Strict, those the database
+// lacks, or that Notion rejects, are left out.
+// cover, if non-nil, is called only when the page is created and may return "" for no cover.
+// It returns the page ID.
+func (n *NotionClient) ensurePage(title string, props map[string]any, cover func() string, build func(existing map[string]bool) []map[string]any) (string, error) {
+	if err := n.ensureSchema(); err != nil && n.Strict {
+		return "", fmt.Errorf("load database schema: %w", err)
+	}
+	existing, err := n.findPageByTitle(title)
 	if err != nil {
-		return fmt.Errorf("check existing page: %w", err)
+		return "", fmt.Errorf("check existing page: %w", err)
 	}
-	if exists {
-		return nil
+	if existing != "" {
+		if n.Replace {
+			defer n.finishPage()()
+			if err := n.clearPage(existing); err != nil {
+				return "", fmt.Errorf("clear existing page: %w", err)
+			}
+			return existing, n.appendBlocks(existing, build(nil))
+		}
+		quotes, err := n.existingQuotes(existing)
+		if err != nil {
+			return "", fmt.Errorf("read existing page: %w", err)
+		}
+		defer n.finishPage()()
+		return existing, n.appendBlocks(existing, build(quotes))
 	}
-	props := map[string]any{n.titlePropName: map[string]any{"title": []map[string]any{{"text": map[string]string{"content": notionTitle}}}}}
-	if author != "" {
-		props["Author"] = map[string]any{"rich_text": []map[string]any{{"text": map[string]string{"content": author}}}}
+	coverURL := ""
+	if cover != nil {
+		coverURL = cover()
+		if err := n.context().Err(); err != nil {
+			return "", err
+		}
 	}
-	payload := map[string]any{"parent": map[string]string{"database_id": n.databaseID}, "properties": props}
-	body, err := json.Marshal(payload)
+	// From here on the page is being written: finish it even if the export is cancelled.
+	defer n.finishPage()()
+	pageID, err := n.createPage(title, props, coverURL)
 	if err != nil {
-		return fmt.Errorf("marshal notion payload: %w", err)
+		return "", err
+	}
+	n.rememberPage(title, pageID)
+	return pageID, n.appendBlocks(pageID, build(nil))
+}
+
+// finishPage detaches the client's requests from cancellation until the returned
+// function restores the export context; the HTTP client timeout still applies.
+func (n *NotionClient) finishPage() (restore func()) {
+	ctx := n.ctx
+	n.ctx = context.WithoutCancel(n.context())
+	return func() { n.ctx = ctx }
+}
+
+// context returns the context for the client's requests.
+func (n *NotionClient) context() context.Context {
+	if n.ctx == nil {
+		return context.Background()
+	}
+	return n.ctx
+}
+
+// missingHighlights returns the highlights whose text is not among existing quote texts.
+func missingHighlights(highlights []Highlight, existing map[string]bool) []Highlight {
+	if len(existing) == 0 {
+		return highlights
 	}
-	createReq := func(p []byte) (*http.Response, error) {
-		req, err := http.NewRequest("POST", "https://api.notion.com/v1/pages", bytes.NewReader(p))
+	out := make([]Highlight, 0, len(highlights))
+	for _, h := range highlights {
+		if !existing[strings.TrimSpace(h.Text)] {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+// existingQuotes lists the text of every highlight-shaped block (quote, callout or
+// paragraph) on a page, across all result pages, so a changed --notion-block-type
+// still recognises highlights synced before.
+func (n *NotionClient) existingQuotes(pageID string) (map[string]bool, error) {
+	quotes := map[string]bool{}
+	cursor := ""
+	for {
+		u := fmt.Sprintf("%s/blocks/%s/children?page_size=100", notionAPI, pageID)
+		if cursor != "" {
+			u += "&start_cursor=" + url.QueryEscape(cursor)
+		}
+		resp, err := n.do("GET", u, nil)
 		if err != nil {
-			return nil, fmt.Errorf("build notion request: %w", err)
+			return nil, fmt.Errorf("perform block list request: %w", err)
+		}
+		if resp.StatusCode >= 300 {
+			b, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, fmt.Errorf("notion block list error: %s – %s", resp.Status, truncateForLog(string(b), 300))
+		}
+		var list struct {
+			Results    []map[string]json.RawMessage `json:"results"`
+			HasMore    bool                         `json:"has_more"`
+			NextCursor string                       `json:"next_cursor"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decode block list: %w", err)
+		}
+		for _, b := range list.Results {
+			var blockType string
+			_ = json.Unmarshal(b["type"], &blockType)
+			if blockType != "quote" && blockType != "callout" && blockType != "paragraph" {
+				continue
+			}
+			var content struct {
+				RichText []struct {
+					PlainText string `json:"plain_text"`
+					Text      struct {
+						Content string `json:"content"`
+					} `json:"text"`
+				} `json:"rich_text"`
+			}
+			if json.Unmarshal(b[blockType], &content) != nil || len(content.RichText) == 0 {
+				continue
+			}
+			var sb strings.Builder
+			for _, rt := range content.RichText {
+				if rt.PlainText != "" {
+					sb.WriteString(rt.PlainText)
+				} else {
+					sb.WriteString(rt.Text.Content)
+				}
+			}
+			quotes[strings.TrimSpace(sb.String())] = true
+		}
+		if !list.HasMore || list.NextCursor == "" {
+			return quotes, nil
+		}
+		cursor = list.NextCursor
+	}
+}
+
+// clearPage deletes every child block of a page (Notion moves them to the trash).
+// All IDs are listed first, since deleting while paging would shift the cursor.
+func (n *NotionClient) clearPage(pageID string) error {
+	var ids []string
+	cursor := ""
+	for {
+		u := fmt.Sprintf("%s/blocks/%s/children?page_size=100", notionAPI, pageID)
+		if cursor != "" {
+			u += "&start_cursor=" + url.QueryEscape(cursor)
+		}
+		resp, err := n.do("GET", u, nil)
+		if err != nil {
+			return fmt.Errorf("perform block list request: %w", err)
+		}
+		if resp.StatusCode >= 300 {
+			b, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return fmt.Errorf("notion block list error: %s – %s", resp.Status, truncateForLog(string(b), 300))
+		}
+		var list struct {
+			Results []struct {
+				ID string `json:"id"`
+			} `json:"results"`
+			HasMore    bool   `json:"has_more"`
+			NextCursor string `json:"next_cursor"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("decode block list: %w", err)
+		}
+		for _, b := range list.Results {
+			ids = append(ids, b.ID)
+		}
+		if !list.HasMore || list.NextCursor == "" {
+			break
+		}
+		cursor = list.NextCursor
+	}
+	for _, id := range ids {
+		resp, err := n.do("DELETE", fmt.Sprintf("%s/blocks/%s", notionAPI, id), nil)
+		if err != nil {
+			return fmt.Errorf("perform block delete request: %w", err)
+		}
+		if resp.StatusCode >= 300 {
+			b, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return fmt.Errorf("notion block delete error: %s – %s", resp.Status, truncateForLog(string(b), 300))
+		}
+		resp.Body.Close()
+	}
+	return nil
+}
+
+// linkSeries sets the "Next in Series" relation on each volume whose successor is
+// also in books. It is skipped when the database has no such relation property.
+func (n *NotionClient) linkSeries(books []Book, pageIDs []string) error {
+	if n.schema[seriesRelationProp] != "relation" {
+		return nil
+	}
+	type volume struct {
+		number float64
+		pageID string
+	}
+	series := map[string][]volume{}
+	for i, b := range books {
+		num, err := strconv.ParseFloat(strings.TrimSpace(b.SeriesNumber), 64)
+		if b.Series == "" || err != nil || pageIDs[i] == "" {
+			continue
+		}
+		key := b.Series + "\x00" + b.Author
+		series[key] = append(series[key], volume{num, pageIDs[i]})
+	}
+	for _, vols := range series {
+		sort.Slice(vols, func(i, j int) bool { return vols[i].number < vols[j].number })
+		for i := 0; i+1 < len(vols); i++ {
+			props := map[string]any{seriesRelationProp: map[string]any{"relation": []map[string]string{{"id": vols[i+1].pageID}}}}
+			resp, err := n.do("PATCH", fmt.Sprintf("%s/pages/%s", notionAPI, vols[i].pageID), map[string]any{"properties": props})
+			if err != nil {
+				return fmt.Errorf("perform series relation update: %w", err)
+			}
+			if resp.StatusCode >= 300 {
+				b, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				return fmt.Errorf("notion series relation error: %s – %s", resp.Status, truncateForLog(string(b), 300))
+			}
+			resp.Body.Close()
+		}
+	}
+	return nil
+}
+
+// parent is the parent object of new pages: the database, or ParentPage.
+func (n *NotionClient) parent() map[string]string {
+	if n.ParentPage != "" {
+		return map[string]string{"page_id": n.ParentPage}
+	}
+	return map[string]string{"database_id": n.databaseID}
+}
+
+// createPage creates a database page (or a child page of ParentPage) and returns its ID.
+func (n *NotionClient) createPage(title string, optional map[string]any, coverURL string) (string, error) {
+	schemaKnown := n.ensureSchema() == nil
+	props := map[string]any{n.titlePropName: map[string]any{"title": []map[string]any{{"text": map[string]string{"content": title}}}}}
+	for k, v := range optional {
+		// Without --notion-strict an optional property the database lacks is left out,
+		// so one missing column (e.g. Date) does not cost the page its others.
+		if _, ok := n.schema[k]; ok || n.Strict || !schemaKnown {
+			props[k] = v
+		}
+	}
+	if n.Strict {
+		if err := n.checkProperties(props); err != nil {
+			return "", err
 		}
-		req.Header.Set("Authorization", "Bearer "+n.token)
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Notion-Version", "2022-06-28")
-		return n.httpClient.Do(req)
 	}
-	resp, err := createReq(body)
+	payload := map[string]any{"parent": n.parent(), "properties": props}
+	if coverURL != "" {
+		payload["cover"] = map[string]any{"type": "external", "external": map[string]string{"url": coverURL}}
+	}
+	resp, err := n.do("POST", notionAPI+"/pages", payload)
 	if err != nil {
-		return fmt.Errorf("perform notion request: %w", err)
+		return "", fmt.Errorf("perform notion request: %w", err)
 	}
-	if resp.StatusCode == 400 && author != "" { // maybe Author property not defined
+	for resp.StatusCode == 400 && !n.Strict {
+		// The schema may be stale or a property mistyped: retry without the one optional
+		// property Notion names in its error, if it names one.
+		b, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		delete(props, "Author")
-		payload["properties"] = props
-		body2, _ := json.Marshal(payload)
-		resp, err = createReq(body2)
+		rejected := rejectedProperty(b, props, optional)
+		if rejected == "" {
+			return "", fmt.Errorf("notion create page error: %s – %s", resp.Status, truncateForLog(string(b), 300))
+		}
+		delete(props, rejected)
+		resp, err = n.do("POST", notionAPI+"/pages", payload)
 		if err != nil {
-			return fmt.Errorf("retry notion request (without Author): %w", err)
+			return "", fmt.Errorf("retry notion request (without %s): %w", rejected, err)
 		}
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
 		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("notion create page error: %s – %s", resp.Status, truncateForLog(string(b), 300))
+		return "", fmt.Errorf("notion create page error: %s – %s", resp.Status, truncateForLog(string(b), 300))
 	}
 	var pageResp struct {
-		ID string `json:"id"`
+		ID  string `json:"id"`
+		URL string `json:"url"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&pageResp); err != nil {
-		return fmt.Errorf("decode page create response: %w", err)
+		return "", fmt.Errorf("decode page create response: %w", err)
+	}
+	if pageResp.ID == "" {
+		return "", fmt.Errorf("no page ID returned from Notion")
+	}
+	if pageResp.URL == "" {
+		pageResp.URL = "https://www.notion.so/" + strings.ReplaceAll(pageResp.ID, "-", "")
+	}
+	n.created = append(n.created, Output{URL: pageResp.URL})
+	return pageResp.ID, nil
+}
+
+// rejectedProperty returns the optional property still in props that a 400 error body
+// blames ("Date is not a property that exists.", "Date is expected to be rich_text."),
+// or "" when the error is about something else.
+func rejectedProperty(body []byte, props, optional map[string]any) string {
+	var apiErr struct {
+		Message string `json:"message"`
 	}
-	pageID := pageResp.ID
-	if pageID == "" {
-		return fmt.Errorf("no page ID returned from Notion")
+	if json.Unmarshal(body, &apiErr) != nil {
+		return ""
+	}
+	for k := range optional {
+		if _, ok := props[k]; ok && strings.HasPrefix(apiErr.Message, k+" is ") {
+			return k
+		}
+	}
+	return ""
+}
+
+// chapterBlocks renders highlights as highlightBlocks, preceded per chapter by a
+// heading of the given type when Chapters is set. Highlights without a chapter get no heading.
+func (n *NotionClient) chapterBlocks(highlights []Highlight, heading string) []map[string]any {
+	if !n.Chapters {
+		return n.highlightBlocks(highlights)
+	}
+	var blocks []map[string]any
+	for _, g := range groupByChapter(highlights) {
+		if g.Title != "" {
+			blocks = append(blocks, headingBlock(heading, g.Title))
+		}
+		blocks = append(blocks, n.highlightBlocks(g.Highlights)...)
+	}
+	return blocks
+}
+
+// headingBlock returns a heading block (heading_1, heading_2 or heading_3) with plain text.
+func headingBlock(heading, text string) map[string]any {
+	return map[string]any{
+		"object": "block",
+		"type":   heading,
+		heading:  map[string]any{"rich_text": []map[string]any{{"type": "text", "text": map[string]string{"content": text}}}},
+	}
+}
+
+// highlightBlocks renders highlights as BlockType blocks separated by blank paragraphs.
+func (n *NotionClient) highlightBlocks(highlights []Highlight) []map[string]any {
+	blockType := n.BlockType
+	if blockType == "" {
+		blockType = notionBlockQuote
 	}
 	blocks := make([]map[string]any, 0, len(highlights)*2)
 	for i, h := range highlights {
+		content := map[string]any{"rich_text": highlightRichText(h)}
+		if blockType == notionBlockCallout && n.CalloutIcon != "" {
+			content["icon"] = map[string]string{"type": "emoji", "emoji": n.CalloutIcon}
+		}
+		if n.Colors && h.Color != "" {
+			content["color"] = h.Color + "_background"
+		}
 		blocks = append(blocks, map[string]any{
-			"object": "block",
-			"type":   "quote",
-			"quote":  map[string]any{"rich_text": []map[string]any{{"type": "text", "text": map[string]string{"content": h}}}},
+			"object":  "block",
+			"type":    blockType,
+			blockType: content,
 		})
+		if date := highlightDate(h); n.IncludeDates && date != "" {
+			blocks = append(blocks, map[string]any{
+				"object": "block",
+				"type":   "paragraph",
+				"paragraph": map[string]any{"rich_text": []map[string]any{
+					{"type": "text", "text": map[string]string{"content": date}, "annotations": map[string]any{"color": "gray", "italic": true}},
+				}},
+			})
+		}
+		if h.Note != "" {
+			blocks = append(blocks, map[string]any{
+				"object": "block",
+				"type":   "paragraph",
+				"paragraph": map[string]any{"rich_text": append([]map[string]any{
+					{"type": "text", "text": map[string]string{"content": "Note: "}, "annotations": map[string]bool{"bold": true}},
+				}, textSegments(h.Note, nil)...)},
+			})
+		}
 		if i < len(highlights)-1 {
 			blocks = append(blocks, map[string]any{"object": "block", "type": "paragraph", "paragraph": map[string]any{"rich_text": []map[string]any{}}})
 		}
 	}
+	return blocks
+}
+
+// highlightDate formats a highlight's date for captions ("January 2, 2006"); a date
+// that could not be parsed is shown as stored.
+func highlightDate(h Highlight) string {
+	if !h.Time.IsZero() {
+		return h.Time.Format("January 2, 2006")
+	}
+	return strings.TrimSpace(h.Date)
+}
+
+// highlightRichText returns the rich_text array for a highlight, carrying bold/italic
+// annotations when emphasis runs are present.
+func highlightRichText(h Highlight) []map[string]any {
+	if len(h.Runs) == 0 {
+		return textSegments(h.Text, nil)
+	}
+	rt := make([]map[string]any, 0, len(h.Runs))
+	for _, r := range h.Runs {
+		var annotations map[string]bool
+		if r.Bold || r.Italic {
+			annotations = map[string]bool{"bold": r.Bold, "italic": r.Italic}
+		}
+		rt = append(rt, textSegments(r.Text, annotations)...)
+	}
+	return rt
+}
+
+// notionTextLimit is the most characters (UTF-16 code units) Notion accepts in one rich text object.
+const notionTextLimit = 2000
+
+// textSegments returns rich text objects for content, split into several objects when it
+// exceeds notionTextLimit; a block may hold many, so long highlights stay in one quote.
+func textSegments(content string, annotations map[string]bool) []map[string]any {
+	var segs []map[string]any
+	for _, chunk := range splitNotionText(content) {
+		seg := map[string]any{"type": "text", "text": map[string]string{"content": chunk}}
+		if annotations != nil {
+			seg["annotations"] = annotations
+		}
+		segs = append(segs, seg)
+	}
+	return segs
+}
+
+// splitNotionText cuts s into chunks of at most notionTextLimit UTF-16 code units,
+// never splitting a rune.
+func splitNotionText(s string) []string {
+	var chunks []string
+	start, units := 0, 0
+	for i, r := range s {
+		n := utf16.RuneLen(r)
+		if n < 0 {
+			n = 1
+		}
+		if units+n > notionTextLimit {
+			chunks = append(chunks, s[start:i])
+			start, units = i, 0
+		}
+		units += n
+	}
+	return append(chunks, s[start:])
+}
+
+// appendBlocks appends children to a page in batches of 100 (Notion API limit).
+func (n *NotionClient) appendBlocks(pageID string, blocks []map[string]any) error {
 	for i := 0; i < len(blocks); i += 100 {
 		end := i + 100
 		if end > len(blocks) {
 			end = len(blocks)
 		}
 		batch := blocks[i:end]
-		appendPayload := map[string]any{"children": batch}
-		appendBody, err := json.Marshal(appendPayload)
-		if err != nil {
-			return fmt.Errorf("marshal append payload: %w", err)
-		}
-		url := fmt.Sprintf("https://api.notion.com/v1/blocks/%s/children", pageID)
-		req, err := http.NewRequest("PATCH", url, bytes.NewReader(appendBody))
-		if err != nil {
-			return fmt.Errorf("build append request: %w", err)
-		}
-		req.Header.Set("Authorization", "Bearer "+n.token)
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Notion-Version", "2022-06-28")
-		resp, err := n.httpClient.Do(req)
+		url := fmt.Sprintf("%s/blocks/%s/children", notionAPI, pageID)
+		resp, err := n.do("PATCH", url, map[string]any{"children": batch})
 		if err != nil {
 			return fmt.Errorf("perform append request: %w", err)
 		}
-		defer resp.Body.Close()
 		if resp.StatusCode >= 300 {
 			b, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
 			return fmt.Errorf("notion append error: %s – %s", resp.Status, truncateForLog(string(b), 300))
 		}
+		resp.Body.Close()
 	}
 	return nil
 }
 
-func (n *NotionClient) pageExistsByTitle(title string) (bool, error) {
-	if !n.resolvedTitle {
-		_ = n.resolveTitlePropertyName()
+// do sends an authenticated Notion API request; payload (if non-nil) is sent as JSON.
+func (n *NotionClient) do(method, url string, payload any) (*http.Response, error) {
+	var data []byte
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("marshal notion payload: %w", err)
+		}
+		data = b
 	}
-	queryPayload := map[string]any{"page_size": 1, "filter": map[string]any{"property": n.titlePropName, "title": map[string]any{"equals": title}}}
-	body, err := json.Marshal(queryPayload)
-	if err != nil {
-		return false, fmt.Errorf("marshal query payload: %w", err)
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		var body io.Reader
+		if data != nil {
+			body = bytes.NewReader(data)
+		}
+		req, err := http.NewRequestWithContext(n.context(), method, url, body)
+		if err != nil {
+			return nil, fmt.Errorf("build notion request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+n.token)
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("Notion-Version", notionVersion)
+		resp, err := n.httpClient.Do(req)
+		if err == nil {
+			Verbosef("notion: %s %s -> %s", method, url, resp.Status)
+		}
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt >= n.MaxRetries {
+			return resp, err
+		}
+		wait := backoff
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+			wait = time.Duration(secs) * time.Second
+		}
+		resp.Body.Close()
+		select {
+		case <-n.context().Done():
+			return nil, n.context().Err()
+		case <-time.After(wait):
+		}
+		backoff *= 2
 	}
-	req, err := http.NewRequest("POST", fmt.Sprintf("https://api.notion.com/v1/databases/%s/query", n.databaseID), bytes.NewReader(body))
-	if err != nil {
-		return false, fmt.Errorf("build query request: %w", err)
+}
+
+// findPageByTitle returns the ID of the page with the given title, or "" if none exists.
+func (n *NotionClient) findPageByTitle(title string) (string, error) {
+	_ = n.ensureSchema()
+	if n.ParentPage != "" && n.cacheState == cacheUnloaded {
+		// A page cannot be queried by title: list its child pages once.
+		if err := n.loadChildPages(); err != nil {
+			return "", err
+		}
+	}
+	if n.CacheAll && n.cacheState == cacheUnloaded {
+		if err := n.loadPageCache(); err != nil {
+			return "", err
+		}
+	}
+	if n.cacheState == cacheReady {
+		return n.pageCache[title], nil
 	}
-	req.Header.Set("Authorization", "Bearer "+n.token)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Notion-Version", "2022-06-28")
-	resp, err := n.httpClient.Do(req)
+	if id, ok := n.lookups[title]; ok {
+		return id, nil
+	}
+	queryPayload := map[string]any{"page_size": 1, "filter": map[string]any{"property": n.titlePropName, "title": map[string]any{"equals": title}}}
+	resp, err := n.do("POST", fmt.Sprintf("%s/databases/%s/query", notionAPI, n.databaseID), queryPayload)
 	if err != nil {
-		return false, fmt.Errorf("perform query: %w", err)
+		return "", fmt.Errorf("perform query: %w", err)
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
 		b, _ := io.ReadAll(resp.Body)
-		return false, fmt.Errorf("query API error: %s – %s", resp.Status, truncateForLog(string(b), 200))
+		return "", fmt.Errorf("query API error: %s – %s", resp.Status, truncateForLog(string(b), 200))
 	}
 	var qr struct {
 		Results []struct {
@@ -187,26 +997,230 @@ func (n *NotionClient) pageExistsByTitle(title string) (bool, error) {
 		} `json:"results"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&qr); err != nil {
-		return false, fmt.Errorf("decode query response: %w", err)
+		return "", fmt.Errorf("decode query response: %w", err)
+	}
+	id := ""
+	if len(qr.Results) > 0 {
+		id = qr.Results[0].ID
+	}
+	n.rememberPage(title, id)
+	return id, nil
+}
+
+// rememberPage records a title's page ID so later existence checks skip the API.
+func (n *NotionClient) rememberPage(title, id string) {
+	if n.cacheState == cacheReady {
+		n.pageCache[title] = id
+		return
+	}
+	if n.lookups == nil {
+		n.lookups = map[string]string{}
+	}
+	n.lookups[title] = id
+}
+
+// loadPageCache lists the database once for existence checks. It gives up (leaving
+// per-title queries in charge) once more than CacheLimit pages are seen.
+func (n *NotionClient) loadPageCache() error {
+	pages, truncated, err := n.listPages(n.CacheLimit)
+	if err != nil {
+		return err
 	}
-	return len(qr.Results) > 0, nil
+	if truncated {
+		n.cacheState = cacheTooLarge
+		return nil
+	}
+	n.pageCache = pages
+	n.cacheState = cacheReady
+	return nil
 }
 
-func (n *NotionClient) resolveTitlePropertyName() error {
-	url := fmt.Sprintf("https://api.notion.com/v1/databases/%s", n.databaseID)
-	req, err := http.NewRequest("GET", url, nil)
+// loadChildPages fills the page cache with the titles of ParentPage's child pages.
+func (n *NotionClient) loadChildPages() error {
+	pages, err := n.listBookPages()
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Authorization", "Bearer "+n.token)
-	req.Header.Set("Notion-Version", "2022-06-28")
-	resp, err := n.httpClient.Do(req)
+	n.pageCache = pages
+	n.cacheState = cacheReady
+	return nil
+}
+
+// listBookPages returns the title -> ID of every page in the database (or every child
+// page of ParentPage), following next_cursor until has_more is false, so large
+// databases are never cut off at one result page.
+func (n *NotionClient) listBookPages() (map[string]string, error) {
+	pages, _, err := n.listPages(0)
+	return pages, err
+}
+
+// listPages is listBookPages stopping early (truncated) once more than limit pages
+// were seen; limit <= 0 lists everything.
+func (n *NotionClient) listPages(limit int) (pages map[string]string, truncated bool, err error) {
+	if n.ParentPage != "" {
+		pages, err = n.listChildPages()
+		return pages, false, err
+	}
+	_ = n.ensureSchema()
+	pages = map[string]string{}
+	cursor := ""
+	for {
+		payload := map[string]any{"page_size": 100}
+		if cursor != "" {
+			payload["start_cursor"] = cursor
+		}
+		resp, err := n.do("POST", fmt.Sprintf("%s/databases/%s/query", notionAPI, n.databaseID), payload)
+		if err != nil {
+			return nil, false, fmt.Errorf("perform query: %w", err)
+		}
+		if resp.StatusCode >= 300 {
+			b, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, false, fmt.Errorf("query API error: %s – %s", resp.Status, truncateForLog(string(b), 200))
+		}
+		var qr struct {
+			Results []struct {
+				ID         string `json:"id"`
+				Properties map[string]struct {
+					Title []struct {
+						PlainText string `json:"plain_text"`
+					} `json:"title"`
+				} `json:"properties"`
+			} `json:"results"`
+			HasMore    bool   `json:"has_more"`
+			NextCursor string `json:"next_cursor"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&qr)
+		resp.Body.Close()
+		if err != nil {
+			return nil, false, fmt.Errorf("decode query response: %w", err)
+		}
+		for _, page := range qr.Results {
+			var sb strings.Builder
+			for _, t := range page.Properties[n.titlePropName].Title {
+				sb.WriteString(t.PlainText)
+			}
+			pages[sb.String()] = page.ID
+		}
+		if !qr.HasMore || qr.NextCursor == "" {
+			return pages, false, nil
+		}
+		if limit > 0 && len(pages) >= limit {
+			return pages, true, nil
+		}
+		cursor = qr.NextCursor
+	}
+}
+
+// listChildPages returns the title -> ID of every child page of ParentPage.
+func (n *NotionClient) listChildPages() (map[string]string, error) {
+	pages := map[string]string{}
+	cursor := ""
+	for {
+		u := fmt.Sprintf("%s/blocks/%s/children?page_size=100", notionAPI, n.ParentPage)
+		if cursor != "" {
+			u += "&start_cursor=" + url.QueryEscape(cursor)
+		}
+		resp, err := n.do("GET", u, nil)
+		if err != nil {
+			return nil, fmt.Errorf("perform block list request: %w", err)
+		}
+		if resp.StatusCode >= 300 {
+			b, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, fmt.Errorf("notion block list error: %s – %s", resp.Status, truncateForLog(string(b), 300))
+		}
+		var list struct {
+			Results []struct {
+				ID        string `json:"id"`
+				Type      string `json:"type"`
+				ChildPage struct {
+					Title string `json:"title"`
+				} `json:"child_page"`
+			} `json:"results"`
+			HasMore    bool   `json:"has_more"`
+			NextCursor string `json:"next_cursor"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decode block list: %w", err)
+		}
+		for _, b := range list.Results {
+			if b.Type == "child_page" {
+				pages[b.ChildPage.Title] = b.ID
+			}
+		}
+		if !list.HasMore || list.NextCursor == "" {
+			return pages, nil
+		}
+		cursor = list.NextCursor
+	}
+}
+
+// ensureSchema loads the database schema once it is first needed; later calls (also
+// from other goroutines) wait for that load and return its error.
+func (n *NotionClient) ensureSchema() error {
+	n.schemaOnce.Do(func() { n.schemaErr = n.loadSchema() })
+	return n.schemaErr
+}
+
+// checkProperties verifies every property in a create payload exists in the schema
+// with the type the payload uses (the single key of each property value).
+func (n *NotionClient) checkProperties(props map[string]any) error {
+	var mismatches []string
+	for name, v := range props {
+		value, _ := v.(map[string]any)
+		want := ""
+		for k := range value {
+			want = k
+		}
+		got, ok := n.schema[name]
+		switch {
+		case !ok:
+			mismatches = append(mismatches, fmt.Sprintf("%q missing (want %s)", name, want))
+		case got != want:
+			mismatches = append(mismatches, fmt.Sprintf("%q is %s (want %s)", name, got, want))
+		}
+	}
+	if len(mismatches) == 0 {
+		return nil
+	}
+	sort.Strings(mismatches)
+	return fmt.Errorf("notion database schema mismatch: %s", strings.Join(mismatches, "; "))
+}
+
+// loadSchema fetches the database properties and resolves the title property name.
+// A parent page has no schema: its child pages only have the "title" property.
+func (n *NotionClient) loadSchema() error {
+	if n.ParentPage != "" {
+		n.schema = map[string]string{"title": "title"}
+		n.titlePropName = "title"
+		return nil
+	}
+	schema, err := n.fetchSchema()
 	if err != nil {
 		return err
 	}
+	n.schema = schema
+	for name, typ := range schema {
+		if typ == "title" {
+			n.titlePropName = name
+		}
+	}
+	return nil
+}
+
+// fetchSchema returns the property name -> type map of the database.
+func (n *NotionClient) fetchSchema() (map[string]string, error) {
+	resp, err := n.do("GET", fmt.Sprintf("%s/databases/%s", notionAPI, n.databaseID), nil)
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
-		return fmt.Errorf("fetch database failed: %s", resp.Status)
+		b, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("fetch database failed: %s – %s", resp.Status, truncateForLog(string(b), 200))
 	}
 	var db struct {
 		Properties map[string]struct {
@@ -214,20 +1228,43 @@ func (n *NotionClient) resolveTitlePropertyName() error {
 		} `json:"properties"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&db); err != nil {
-		return err
+		return nil, err
 	}
+	schema := make(map[string]string, len(db.Properties))
 	for name, meta := range db.Properties {
-		if meta.Type == "title" {
-			if name != "Title" {
-				n.titlePropName = name
-			}
-			break
+		schema[name] = meta.Type
+	}
+	return schema, nil
+}
+
+// Preflight checks access to, and the schema of, the target database before anything
+// is created. For a parent page it checks that the page can be read.
+func (n *NotionClient) Preflight() error {
+	if n.ParentPage != "" {
+		if err := n.loadChildPages(); err != nil {
+			return fmt.Errorf("notion preflight failed (share the parent page with the integration): %w", err)
 		}
+		return nil
+	}
+	schema, err := n.fetchSchema()
+	if err != nil {
+		return fmt.Errorf("notion preflight failed (share the database with the integration): %w", err)
+	}
+	if !hasTitleProperty(schema) {
+		return fmt.Errorf("notion preflight failed: database %s has no title property", n.databaseID)
 	}
-	n.resolvedTitle = true
 	return nil
 }
 
+func hasTitleProperty(schema map[string]string) bool {
+	for _, typ := range schema {
+		if typ == "title" {
+			return true
+		}
+	}
+	return false
+}
+
 func truncateForLog(s string, max int) string {
 	if len(s) <= max {
 		return s
@@ -251,17 +1288,180 @@ func (notionDBFlag) CLIFlag() any {
 	return &cli.StringFlag{Name: "notion-database", Usage: "Notion database ID (or NOTION_DB)", EnvVars: []string{"NOTION_DB"}}
 }
 
+type notionStrictFlag struct{}
+
+func (notionStrictFlag) CLIFlag() any {
+	return &cli.BoolFlag{Name: "notion-strict", Usage: "Fail when a property to write is missing or mistyped in the Notion database"}
+}
+
+type notionCacheAllFlag struct{}
+
+func (notionCacheAllFlag) CLIFlag() any {
+	return &cli.BoolFlag{Name: "notion-query-cache-all", Usage: "List existing Notion pages once instead of querying per book"}
+}
+
+type notionCacheLimitFlag struct{}
+
+func (notionCacheLimitFlag) CLIFlag() any {
+	return &cli.IntFlag{Name: "notion-cache-limit", Value: 10000, Usage: "Fall back to per-book queries when the database has more pages than this"}
+}
+
+type notionSeriesRelationsFlag struct{}
+
+func (notionSeriesRelationsFlag) CLIFlag() any {
+	return &cli.BoolFlag{Name: "notion-series-relations", Usage: "Link series volumes through a \"Next in Series\" self-relation (skipped if the property is absent)"}
+}
+
+type notionPreflightFlag struct{}
+
+func (notionPreflightFlag) CLIFlag() any {
+	return &cli.BoolFlag{Name: "notion-preflight", Usage: "Verify access to and schema of the target database before creating pages"}
+}
+
+type notionMaxNewFlag struct{}
+
+func (notionMaxNewFlag) CLIFlag() any {
+	return &cli.IntFlag{Name: "notion-max-new", Value: 500, Usage: "Ask before creating more than this many new pages in one run (0 = no limit; --yes skips the prompt)"}
+}
+
+type notionURLPropertyFlag struct{}
+
+func (notionURLPropertyFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "notion-url-property", Usage: "Property (url or text) to receive the Kobo store link of purchased books"}
+}
+
+type notionFailFastFlag struct{}
+
+func (notionFailFastFlag) CLIFlag() any {
+	return &cli.BoolFlag{Name: "fail-fast", Usage: "Stop the Notion sync at the first page that fails (default: sync the rest, then report every failure and exit non-zero)"}
+}
+
+type notionCoversFlag struct{}
+
+func (notionCoversFlag) CLIFlag() any {
+	return &cli.BoolFlag{Name: "notion-covers", Usage: "Set new book pages' cover from the book's metadata (Kobo store image; sideloaded books get none unless --notion-cover-lookup finds one)"}
+}
+
+type notionCoverLookupFlag struct{}
+
+func (notionCoverLookupFlag) CLIFlag() any {
+	return &cli.BoolFlag{Name: "notion-cover-lookup", Usage: "Set new book pages' cover from Open Library (looked up by title and author)"}
+}
+
+type notionMaxRetriesFlag struct{}
+
+func (notionMaxRetriesFlag) CLIFlag() any {
+	return &cli.IntFlag{Name: "notion-max-retries", Value: 5, Usage: "Retries for a rate-limited (HTTP 429) Notion request, honouring Retry-After (0 = fail immediately)"}
+}
+
+type notionChaptersFlag struct{}
+
+func (notionChaptersFlag) CLIFlag() any {
+	return &cli.BoolFlag{Name: "notion-chapters", Usage: "Group highlights under a heading per chapter"}
+}
+
+type notionBlockTypeFlag struct{}
+
+func (notionBlockTypeFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "notion-block-type", Value: notionBlockQuote, Usage: "Block used for each highlight: quote, callout or paragraph"}
+}
+
+type notionCalloutIconFlag struct{}
+
+func (notionCalloutIconFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "notion-callout-icon", Value: "📖", Usage: "Emoji icon of callout blocks (with --notion-block-type callout; empty for Notion's default)"}
+}
+
+type notionIncludeDatesFlag struct{}
+
+func (notionIncludeDatesFlag) CLIFlag() any {
+	return &cli.BoolFlag{Name: "notion-include-dates", Usage: "Add a gray caption with the highlight date after each highlight"}
+}
+
+type notionColorsFlag struct{}
+
+func (notionColorsFlag) CLIFlag() any {
+	return &cli.BoolFlag{Name: "notion-colors", Usage: "Give each highlight block the background of its highlight color"}
+}
+
+type notionParentPageFlag struct{}
+
+func (notionParentPageFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "notion-parent-page", Usage: "Notion page ID to create book pages under as sub-pages (instead of database rows)"}
+}
+
+type notionModeFlag struct{}
+
+func (notionModeFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "notion-mode", Value: notionModeAppend, Usage: "For existing pages: append (only missing highlights) or replace (delete the page's blocks and write all current highlights)"}
+}
+
+type notionGroupByFlag struct{}
+
+func (notionGroupByFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "notion-group-by", Value: notionGroupBook, Usage: "One Notion page per: book or author"}
+}
+
 func init() {
 	RegisterFormat(&FormatFactory{
 		Name:  "notion",
-		Flags: []FlagProvider{notionTokenFlag{}, notionDBFlag{}},
+		Flags: []FlagProvider{notionTokenFlag{}, notionDBFlag{}, notionParentPageFlag{}, notionModeFlag{}, notionStrictFlag{}, notionGroupByFlag{}, notionCacheAllFlag{}, notionCacheLimitFlag{}, notionSeriesRelationsFlag{}, notionPreflightFlag{}, notionMaxNewFlag{}, notionFailFastFlag{}, notionURLPropertyFlag{}, notionCoversFlag{}, notionCoverLookupFlag{}, notionMaxRetriesFlag{}, notionChaptersFlag{}, notionBlockTypeFlag{}, notionCalloutIconFlag{}, notionIncludeDatesFlag{}, notionColorsFlag{}},
 		Build: func(r FlagValueResolver) (Format, error) {
 			token := strings.TrimSpace(r.String("notion-token"))
 			dbid := strings.TrimSpace(r.String("notion-database"))
-			if token == "" || dbid == "" {
-				return nil, fmt.Errorf("--notion-token and --notion-database required for format notion")
+			parent := strings.TrimSpace(r.String("notion-parent-page"))
+			if token == "" || (dbid == "" && parent == "") {
+				return nil, fmt.Errorf("--notion-token and --notion-database (or --notion-parent-page) required for format notion")
+			}
+			if parent != "" && dbid != "" {
+				Infof("--notion-parent-page set; creating sub-pages instead of rows in --notion-database")
+			}
+			if parent != "" && r.Bool("notion-series-relations") {
+				return nil, fmt.Errorf("--notion-series-relations needs a database (relations are properties, which sub-pages do not have)")
+			}
+			groupBy := strings.ToLower(strings.TrimSpace(r.String("notion-group-by")))
+			if groupBy == "" {
+				groupBy = notionGroupBook
+			}
+			if groupBy != notionGroupBook && groupBy != notionGroupAuthor {
+				return nil, fmt.Errorf("--notion-group-by must be %s or %s", notionGroupBook, notionGroupAuthor)
+			}
+			mode := strings.ToLower(strings.TrimSpace(r.String("notion-mode")))
+			if mode != "" && mode != notionModeAppend && mode != notionModeReplace {
+				return nil, fmt.Errorf("--notion-mode must be %s or %s", notionModeAppend, notionModeReplace)
+			}
+			client := NewNotionClient(token, dbid)
+			client.ParentPage = parent
+			client.Replace = mode == notionModeReplace
+			client.Strict = r.Bool("notion-strict")
+			client.CacheAll = r.Bool("notion-query-cache-all")
+			client.CacheLimit = r.Int("notion-cache-limit")
+			client.URLProperty = strings.TrimSpace(r.String("notion-url-property"))
+			client.MaxRetries = r.Int("notion-max-retries")
+			client.Chapters = r.Bool("notion-chapters")
+			client.BlockType = strings.ToLower(strings.TrimSpace(r.String("notion-block-type")))
+			if client.BlockType == "" {
+				client.BlockType = notionBlockQuote
+			}
+			if client.BlockType != notionBlockQuote && client.BlockType != notionBlockCallout && client.BlockType != notionBlockParagraph {
+				return nil, fmt.Errorf("--notion-block-type must be %s, %s or %s", notionBlockQuote, notionBlockCallout, notionBlockParagraph)
+			}
+			client.CalloutIcon = strings.TrimSpace(r.String("notion-callout-icon"))
+			client.IncludeDates = r.Bool("notion-include-dates")
+			client.Colors = r.Bool("notion-colors")
+			client.MetadataCovers = r.Bool("notion-covers")
+			if r.Bool("notion-cover-lookup") {
+				client.Covers = NewCoverLookup()
 			}
-			return &NotionFormat{Client: NewNotionClient(token, dbid)}, nil
+			return &NotionFormat{
+				Client:          client,
+				GroupBy:         groupBy,
+				SeriesRelations: r.Bool("notion-series-relations"),
+				Preflight:       r.Bool("notion-preflight"),
+				MaxNew:          r.Int("notion-max-new"),
+				Yes:             r.Bool("yes"),
+				FailFast:        r.Bool("fail-fast"),
+			}, nil
 		},
 	})
 }
@@ -0,0 +1,265 @@
+package formats
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"unicode/utf16"
+)
+
+// stubNotion points notionAPI at a test server running handler and returns a client
+// for it; the real endpoint is restored when the test ends.
+func stubNotion(t *testing.T, handler http.HandlerFunc) *NotionClient {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	orig := notionAPI
+	notionAPI = srv.URL
+	t.Cleanup(func() { notionAPI = orig })
+	n := NewNotionClient("token", "db")
+	n.httpClient = srv.Client()
+	return n
+}
+
+func TestNotionDoRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxRetries int
+		statuses   []int // response per call; the last one repeats
+		wantStatus int
+		wantCalls  int32
+	}{
+		{"429 then 200 is retried", 2, []int{http.StatusTooManyRequests, http.StatusOK}, http.StatusOK, 2},
+		{"retries run out", 2, []int{http.StatusTooManyRequests}, http.StatusTooManyRequests, 3},
+		{"500 is not retried", 3, []int{http.StatusInternalServerError, http.StatusOK}, http.StatusInternalServerError, 1},
+		{"MaxRetries 0 fails right away", 0, []int{http.StatusTooManyRequests, http.StatusOK}, http.StatusTooManyRequests, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			n := stubNotion(t, func(w http.ResponseWriter, r *http.Request) {
+				i := int(calls.Add(1)) - 1
+				status := tt.statuses[min(i, len(tt.statuses)-1)]
+				if status == http.StatusTooManyRequests {
+					w.Header().Set("Retry-After", "0")
+				}
+				w.WriteHeader(status)
+			})
+			n.MaxRetries = tt.maxRetries
+			resp, err := n.do("GET", notionAPI+"/users/me", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("requests = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestNotionAppendLongHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		sizes []int // UTF-16 units per rich text object
+	}{
+		{"5000 ASCII characters", strings.Repeat("a", 5000), []int{2000, 2000, 1000}},
+		{"5000 two-byte characters", strings.Repeat("é", 5000), []int{2000, 2000, 1000}},
+		// The first emoji (a surrogate pair) would straddle unit 2000, so it opens the next object.
+		{"surrogate pair at the boundary", strings.Repeat("a", 1999) + strings.Repeat("😀", 1500), []int{1999, 2000, 1000}},
+		{"three-byte characters up to the boundary", strings.Repeat("€", 2000) + strings.Repeat("😀", 1250), []int{2000, 2000, 500}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []map[string]any
+			n := stubNotion(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != "PATCH" || r.URL.Path != "/blocks/page-1/children" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+				var body struct {
+					Children []map[string]any `json:"children"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("decode body: %v", err)
+				}
+				// Like Notion, reject rich text objects over the limit.
+				for _, b := range body.Children {
+					for _, seg := range richTextOf(b) {
+						if units := len(utf16.Encode([]rune(seg))); units > notionTextLimit {
+							w.WriteHeader(http.StatusBadRequest)
+							return
+						}
+					}
+				}
+				got = append(got, body.Children...)
+				w.Write([]byte(`{}`))
+			})
+			if err := n.appendBlocks("page-1", n.highlightBlocks([]Highlight{{Text: tt.text}})); err != nil {
+				t.Fatalf("append failed: %v", err)
+			}
+			if len(got) != 1 || got[0]["type"] != "quote" {
+				t.Fatalf("blocks = %v, want one quote block", got)
+			}
+			segs := richTextOf(got[0])
+			var sizes []int
+			for _, seg := range segs {
+				sizes = append(sizes, len(utf16.Encode([]rune(seg))))
+			}
+			if strings.Join(segs, "") != tt.text {
+				t.Error("rich text objects do not spell the highlight")
+			}
+			if len(sizes) != len(tt.sizes) {
+				t.Fatalf("sizes = %v, want %v", sizes, tt.sizes)
+			}
+			for i := range sizes {
+				if sizes[i] != tt.sizes[i] {
+					t.Fatalf("sizes = %v, want %v", sizes, tt.sizes)
+				}
+			}
+		})
+	}
+}
+
+// richTextOf returns the text contents of a decoded block's rich_text objects.
+func richTextOf(block map[string]any) []string {
+	content, _ := block[block["type"].(string)].(map[string]any)
+	items, _ := content["rich_text"].([]any)
+	var texts []string
+	for _, it := range items {
+		text, _ := it.(map[string]any)["text"].(map[string]any)
+		s, _ := text["content"].(string)
+		texts = append(texts, s)
+	}
+	return texts
+}
+
+func TestNotionSchemaLoadedOnceUnderConcurrentFirstUse(t *testing.T) {
+	var fetches atomic.Int32
+	n := stubNotion(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" && r.URL.Path == "/databases/db" {
+			fetches.Add(1)
+		}
+		w.Write([]byte(`{"properties": {"Name": {"type": "title"}, "Author": {"type": "rich_text"}}}`))
+	})
+	const goroutines = 16
+	start := make(chan struct{})
+	errs := make([]error, goroutines)
+	var wg sync.WaitGroup
+	for i := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs[i] = n.ensureSchema()
+		}()
+	}
+	close(start)
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("goroutine %d: %v", i, err)
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("GET /databases/db made %d times, want 1", got)
+	}
+	if n.titlePropName != "Name" || n.schema["Author"] != "rich_text" {
+		t.Errorf("schema = %v, title property %q", n.schema, n.titlePropName)
+	}
+}
+
+func TestNotionPreflight(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string // "" = passes
+	}{
+		{"shared database with a title", http.StatusOK, `{"properties": {"Name": {"type": "title"}}}`, ""},
+		{"database not shared", http.StatusNotFound, `{"message": "Could not find database"}`, "share the database with the integration"},
+		{"no title property", http.StatusOK, `{"properties": {"Author": {"type": "rich_text"}}}`, "database db has no title property"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths []string
+			n := stubNotion(t, func(w http.ResponseWriter, r *http.Request) {
+				paths = append(paths, r.Method+" "+r.URL.Path)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+			err := n.Preflight()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Preflight: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("Preflight error = %v, want it to mention %q", err, tt.wantErr)
+			}
+			if len(paths) != 1 || paths[0] != "GET /databases/db" {
+				t.Errorf("requests = %v, want only GET /databases/db", paths)
+			}
+		})
+	}
+}
+
+func TestNotionCreatePageDropsOnlyUnusableProperties(t *testing.T) {
+	author := map[string]any{"rich_text": []map[string]any{{"text": map[string]string{"content": "Frank Herbert"}}}}
+	date := map[string]any{"date": map[string]string{"start": "2023-05-01T10:34:56Z"}}
+	tests := []struct {
+		name     string
+		schema   string
+		rejected string // property the stub answers 400 for while it is sent
+		want     []string
+	}{
+		{"property missing from the schema", `{"Name": {"type": "title"}, "Author": {"type": "rich_text"}}`, "", []string{"Author", "Name"}},
+		{"property Notion rejects", `{"Name": {"type": "title"}, "Author": {"type": "rich_text"}, "Date": {"type": "rich_text"}}`, "Date", []string{"Author", "Name"}},
+		{"every property usable", `{"Name": {"type": "title"}, "Author": {"type": "rich_text"}, "Date": {"type": "date"}}`, "", []string{"Author", "Date", "Name"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent [][]string
+			n := stubNotion(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == "GET" {
+					w.Write([]byte(`{"properties": ` + tt.schema + `}`))
+					return
+				}
+				var body struct {
+					Properties map[string]any `json:"properties"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("decode body: %v", err)
+				}
+				var names []string
+				for k := range body.Properties {
+					names = append(names, k)
+				}
+				sort.Strings(names)
+				sent = append(sent, names)
+				if _, ok := body.Properties[tt.rejected]; ok {
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(`{"object": "error", "status": 400, "code": "validation_error", "message": "` + tt.rejected + ` is expected to be rich_text."}`))
+					return
+				}
+				w.Write([]byte(`{"id": "page-1", "url": "https://www.notion.so/page1"}`))
+			})
+			id, err := n.createPage("Dune", map[string]any{"Author": author, "Date": date}, "")
+			if err != nil {
+				t.Fatalf("createPage: %v", err)
+			}
+			if id != "page-1" {
+				t.Errorf("id = %q", id)
+			}
+			if got := sent[len(sent)-1]; strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("created with properties %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package formats
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// ObsidianFormat writes one note per book into an Obsidian vault folder, with the
+// author as a [[wikilink]], #highlight tags on every quote and a map-of-content note.
+type ObsidianFormat struct {
+	Dir     string
+	MOC     string // MOC note name (without .md)
+	written []Output
+}
+
+// obsidianTag is appended to every quote so highlights are searchable by tag.
+const obsidianTag = "#highlight"
+
+func (o *ObsidianFormat) Name() string { return "obsidian" }
+
+// Outputs lists the notes written so far.
+func (o *ObsidianFormat) Outputs() []Output { return o.written }
+
+func (o *ObsidianFormat) Export(books []Book) error {
+	if err := os.MkdirAll(o.Dir, 0o755); err != nil {
+		return fmt.Errorf("create dir: %w", err)
+	}
+	var moc strings.Builder
+	fmt.Fprintf(&moc, "# %s\n\n", o.MOC)
+	// Note names are unique ignoring case, as Obsidian links are; the MOC's name is
+	// taken first so no book note replaces it.
+	mocName := obsidianFileName(o.MOC)
+	used := map[string]bool{strings.ToLower(mocName): true}
+	for _, b := range books {
+		base := obsidianNoteName(b)
+		name := base
+		for i := 2; used[strings.ToLower(name)]; i++ {
+			name = fmt.Sprintf("%s-%d", base, i)
+		}
+		used[strings.ToLower(name)] = true
+		var note strings.Builder
+		fmt.Fprintf(&note, "# %s\n\n", b.Title)
+		if b.Author != "" {
+			fmt.Fprintf(&note, "Author: %s\n", wikilink(b.Author))
+		}
+		fmt.Fprintf(&note, "Tags: %s\n\n", obsidianTag)
+		writeMarkdownQuotes(&note, b.Highlights, obsidianTag, quoteOptions{})
+		if err := o.write(name, note.String()); err != nil {
+			return err
+		}
+		fmt.Fprintf(&moc, "- [[%s]] (%d)\n", name, len(b.Highlights))
+	}
+	return o.write(mocName, moc.String())
+}
+
+// write stores a note as Dir/<name>.md.
+func (o *ObsidianFormat) write(name, content string) error {
+	path := filepath.Join(o.Dir, name+".md")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("write file %s: %w", path, err)
+	}
+	o.written = append(o.written, Output{Path: path})
+	return nil
+}
+
+// obsidianNoteName is the note title for a book: "Title - Author", made safe as a
+// file name and link target.
+func obsidianNoteName(b Book) string {
+	name := b.Title
+	if b.Author != "" {
+		name += " - " + b.Author
+	}
+	return obsidianFileName(name)
+}
+
+// obsidianFileName drops characters Obsidian rejects in note titles (* " \ / < > : | ?)
+// or that break links (# ^ [ ]) and collapses whitespace; unlike sanitizeFilename it
+// keeps spaces, so note titles stay readable.
+func obsidianFileName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case '*', '"', '\\', '/', '<', '>', ':', '|', '?', '#', '^', '[', ']':
+			return ' '
+		}
+		return r
+	}, s)
+	s = strings.Join(strings.Fields(s), " ")
+	s = strings.TrimLeft(s, ".") // a leading dot would hide the note
+	if s == "" {
+		return "Untitled"
+	}
+	return s
+}
+
+// registration
+type obsidianDirFlag struct{}
+
+func (obsidianDirFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "obsidian-dir", Usage: "Vault folder for Obsidian notes (required when --format obsidian)"}
+}
+
+type obsidianMOCFlag struct{}
+
+func (obsidianMOCFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "obsidian-moc", Value: "Highlights", Usage: "Name of the map-of-content note linking every book note"}
+}
+
+func init() {
+	RegisterFormat(&FormatFactory{
+		Name:  "obsidian",
+		Flags: []FlagProvider{obsidianDirFlag{}, obsidianMOCFlag{}},
+		Build: func(r FlagValueResolver) (Format, error) {
+			dir := strings.TrimSpace(r.String("obsidian-dir"))
+			if dir == "" {
+				return nil, fmt.Errorf("--obsidian-dir required for format obsidian")
+			}
+			moc := strings.TrimSpace(r.String("obsidian-moc"))
+			if moc == "" {
+				moc = "Highlights"
+			}
+			return &ObsidianFormat{Dir: dir, MOC: moc}, nil
+		},
+	})
+}
@@ -0,0 +1,38 @@
+package formats
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestObsidianCollidingNotesGetSeparateFiles(t *testing.T) {
+	dir := t.TempDir()
+	o := &ObsidianFormat{Dir: dir, MOC: "Highlights"}
+	books := []Book{
+		{Title: "Dune: Messiah", Highlights: []Highlight{{Text: "from the colon edition"}}},
+		{Title: "Dune Messiah", Highlights: []Highlight{{Text: "from the plain edition"}}},
+		{Title: "highlights", Highlights: []Highlight{{Text: "from the book named like the MOC"}}},
+	}
+	if err := o.Export(books); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"Dune Messiah.md":   "> from the colon edition",
+		"Dune Messiah-2.md": "> from the plain edition",
+		"highlights-2.md":   "> from the book named like the MOC",
+		"Highlights.md":     "- [[Dune Messiah]] (1)\n- [[Dune Messiah-2]] (1)\n- [[highlights-2]] (1)\n",
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("missing %s: %v", name, err)
+		}
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s does not hold %q:\n%s", name, want, data)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 4 {
+		t.Errorf("wrote %d files, want 4", len(entries))
+	}
+}
@@ -0,0 +1,101 @@
+package formats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// OrgFormat writes all books to one Emacs Org-mode file: a headline per book with the
+// author in a property drawer and each highlight as a quote block.
+type OrgFormat struct {
+	File    string // output path; "-" writes to stdout
+	written []Output
+}
+
+func (o *OrgFormat) Name() string { return "org" }
+
+// WritesStdout reports whether the Org file goes to stdout.
+func (o *OrgFormat) WritesStdout() bool { return o.File == "-" }
+
+// Outputs lists the Org file once written (nothing for stdout).
+func (o *OrgFormat) Outputs() []Output { return o.written }
+
+func (o *OrgFormat) Export(books []Book) error {
+	var out io.Writer = os.Stdout
+	var file *os.File
+	if !o.WritesStdout() {
+		f, err := os.Create(o.File)
+		if err != nil {
+			return fmt.Errorf("create file %s: %w", o.File, err)
+		}
+		file, out = f, f
+	}
+	w := bufio.NewWriter(out)
+	for _, b := range books {
+		fmt.Fprintf(w, "* %s\n", strings.Join(strings.Fields(b.Title), " "))
+		if b.Author != "" {
+			fmt.Fprintf(w, ":PROPERTIES:\n:AUTHOR: %s\n:END:\n", strings.Join(strings.Fields(b.Author), " "))
+		}
+		for _, h := range b.Highlights {
+			text := strings.TrimSpace(h.Text)
+			if text == "" {
+				continue
+			}
+			fmt.Fprintf(w, "\n#+begin_quote\n%s\n#+end_quote\n", orgEscape(text))
+			if h.Note != "" {
+				fmt.Fprintf(w, "\nNote: %s\n", orgEscape(strings.TrimSpace(h.Note)))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	err := w.Flush()
+	if file != nil {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("write org: %w", err)
+	}
+	if !o.WritesStdout() {
+		o.written = append(o.written, Output{Path: o.File})
+	}
+	return nil
+}
+
+// orgLineStart matches line starts Org would read as markup: headlines (*), keywords
+// and block delimiters (#+), drawers and properties (:NAME:) and the comma escape itself.
+var orgLineStart = regexp.MustCompile(`(?m)^(\s*)(,*(\*|#\+|:[A-Za-z_-]+:))`)
+
+// orgEscape prefixes markup-looking line starts with a comma, Org's own escape for
+// block content (org-escape-code-in-string).
+func orgEscape(s string) string {
+	return orgLineStart.ReplaceAllString(s, "$1,$2")
+}
+
+// registration
+type orgFileFlag struct{}
+
+func (orgFileFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "org-file", Usage: "Output file for Org-mode (- for stdout; required when --format org)"}
+}
+
+func init() {
+	RegisterFormat(&FormatFactory{
+		Name:  "org",
+		Flags: []FlagProvider{orgFileFlag{}},
+		Build: func(r FlagValueResolver) (Format, error) {
+			file := strings.TrimSpace(r.String("org-file"))
+			if file == "" {
+				return nil, fmt.Errorf("--org-file required for format org")
+			}
+			return &OrgFormat{File: file}, nil
+		},
+	})
+}
@@ -0,0 +1,124 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// readwiseAPI is the Readwise highlight import endpoint.
+var readwiseAPI = "https://readwise.io/api/v2/highlights/"
+
+// readwiseBatchSize is how many highlights go into one import request.
+const readwiseBatchSize = 100
+
+// ReadwiseFormat imports highlights into Readwise through its bulk highlight API.
+type ReadwiseFormat struct {
+	httpClient *http.Client
+	token      string
+}
+
+// readwiseHighlight is one entry of the import payload.
+type readwiseHighlight struct {
+	Text          string `json:"text"`
+	Title         string `json:"title"`
+	Author        string `json:"author,omitempty"`
+	SourceType    string `json:"source_type"`
+	Category      string `json:"category"`
+	Note          string `json:"note,omitempty"`
+	Location      int    `json:"location"`
+	LocationType  string `json:"location_type"`
+	HighlightedAt string `json:"highlighted_at,omitempty"`
+}
+
+func NewReadwiseFormat(token string) *ReadwiseFormat {
+	return &ReadwiseFormat{httpClient: &http.Client{Timeout: 30 * time.Second}, token: token}
+}
+
+func (r *ReadwiseFormat) Name() string { return "readwise" }
+
+func (r *ReadwiseFormat) Export(books []Book) error {
+	var all []readwiseHighlight
+	for _, b := range books {
+		for i, h := range b.Highlights {
+			rh := readwiseHighlight{
+				Text:         h.Text,
+				Title:        b.Title,
+				Author:       b.Author,
+				SourceType:   "kobo-highlights",
+				Category:     "books",
+				Note:         h.Note,
+				Location:     i + 1,
+				LocationType: "order",
+			}
+			if !h.Time.IsZero() {
+				rh.HighlightedAt = h.Time.Format(time.RFC3339)
+			}
+			all = append(all, rh)
+		}
+	}
+	var failed []string
+	defer EndProgress()
+	for start := 0; start < len(all); start += readwiseBatchSize {
+		end := min(start+readwiseBatchSize, len(all))
+		Progress(end, len(all), all[start].Title)
+		if err := r.post(all[start:end]); err != nil {
+			failed = append(failed, fmt.Sprintf("highlights %d-%d: %v", start+1, end, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("readwise import failed for %d of %d batches:\n  %s", len(failed), (len(all)+readwiseBatchSize-1)/readwiseBatchSize, strings.Join(failed, "\n  "))
+	}
+	return nil
+}
+
+// post sends one batch of highlights.
+func (r *ReadwiseFormat) post(batch []readwiseHighlight) error {
+	data, err := json.Marshal(map[string]any{"highlights": batch})
+	if err != nil {
+		return fmt.Errorf("marshal readwise payload: %w", err)
+	}
+	req, err := http.NewRequest("POST", readwiseAPI, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("build readwise request: %w", err)
+	}
+	req.Header.Set("Authorization", "Token "+r.token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("perform readwise request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s – %s", resp.Status, truncateForLog(string(b), 300))
+	}
+	return nil
+}
+
+// registration
+type readwiseTokenFlag struct{}
+
+func (readwiseTokenFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "readwise-token", Usage: "Readwise access token (or READWISE_TOKEN)", EnvVars: []string{"READWISE_TOKEN"}}
+}
+
+func init() {
+	RegisterFormat(&FormatFactory{
+		Name:  "readwise",
+		Flags: []FlagProvider{readwiseTokenFlag{}},
+		Build: func(r FlagValueResolver) (Format, error) {
+			token := strings.TrimSpace(r.String("readwise-token"))
+			if token == "" {
+				return nil, fmt.Errorf("--readwise-token (or READWISE_TOKEN) required for format readwise")
+			}
+			return NewReadwiseFormat(token), nil
+		},
+	})
+}
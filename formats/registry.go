@@ -1,16 +1,62 @@
 package formats
 
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
 // Domain structs shared by all formats.
 
 type Highlight struct {
-	Text string
-	Date string // raw date string from DB (kept as-is for now)
+	Text    string    `json:"text"`
+	Note    string    `json:"note,omitempty"`    // the reader's own annotation on the highlight, if any
+	Chapter string    `json:"chapter,omitempty"` // chapter title from the table of contents, if resolved
+	Color   string    `json:"color,omitempty"`   // highlight color name (yellow, red, green, blue, pink), if recorded
+	Date    string    `json:"date"`              // DateCreated exactly as stored by the device
+	Time    time.Time `json:"-"`                 // Date parsed by ParseKoboDate; zero if unparseable
+	Runs    []TextRun `json:"-"`                 // optional emphasis runs; concatenated they spell Text
+}
+
+// TextRun is a span of highlight text with uniform emphasis.
+type TextRun struct {
+	Text   string
+	Bold   bool
+	Italic bool
 }
 
 type Book struct {
-	Title      string
-	Author     string
-	Highlights []Highlight
+	Title        string      `json:"title"`
+	Author       string      `json:"author"`
+	Series       string      `json:"series,omitempty"`
+	SeriesNumber string      `json:"series_number,omitempty"` // volume number as stored by the device (e.g. "2", "2.5")
+	Language     string      `json:"language,omitempty"`      // language tag from the book metadata (e.g. "en", "fr-FR")
+	ISBN         string      `json:"isbn,omitempty"`
+	Publisher    string      `json:"publisher,omitempty"`
+	Progress     int         `json:"progress"`            // percent read (0-100); finished books report 100
+	StoreURL     string      `json:"store_url,omitempty"` // store page for purchased books; empty for sideloaded ones
+	CoverURL     string      `json:"cover_url,omitempty"` // cover image for store books; empty for sideloaded ones
+	Words        int         `json:"words"`               // words across the highlight texts (set by CountText)
+	Characters   int         `json:"characters"`          // characters (runes) across the highlight texts, spaces included
+	Highlights   []Highlight `json:"highlights"`
+}
+
+// CountText sets each book's Words and Characters from its highlight texts (trimmed;
+// notes are not counted). Run it once the highlight set is final.
+func CountText(books []Book) []Book {
+	for i := range books {
+		words, chars := 0, 0
+		for _, h := range books[i].Highlights {
+			text := strings.TrimSpace(h.Text)
+			words += len(strings.Fields(text))
+			chars += utf8.RuneCountInString(text)
+		}
+		books[i].Words, books[i].Characters = words, chars
+	}
+	return books
 }
 
 // Format defines a pluggable output format target.
@@ -19,6 +65,59 @@ type Format interface {
 	Name() string
 }
 
+// StdoutWriter is implemented by formats that can write to stdout; when WritesStdout
+// is true the console preview is skipped so it does not mix with the output.
+type StdoutWriter interface{ WritesStdout() bool }
+
+// OutputValidator is implemented by formats with a strict output syntax; ValidateOutput
+// re-reads what Export wrote and reports an error when it does not parse.
+type OutputValidator interface{ ValidateOutput() error }
+
+// Output is one artifact written by an export: a file path or, for remote targets, a URL.
+type Output struct {
+	Path string `json:"path,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+// OutputLister is implemented by formats that report what they wrote; Outputs lists the
+// artifacts completed so far, so it is meaningful after a failed Export too.
+type OutputLister interface{ Outputs() []Output }
+
+// Appender is implemented by formats that can add to output from an earlier Export;
+// after EnableAppend, Export appends to existing files or pages instead of replacing them.
+type Appender interface{ EnableAppend() }
+
+// BookStreamer is implemented by formats that can write books one at a time as a source
+// reads them (--stream): StartStream opens the output, StreamBook writes one book and
+// EndStream flushes and closes the output.
+type BookStreamer interface {
+	StartStream() error
+	StreamBook(b Book) error
+	EndStream() error
+}
+
+// ContextExporter is implemented by formats whose export can be cancelled (network
+// syncs); ExportContext stops at the next safe point once ctx is done.
+type ContextExporter interface {
+	ExportContext(ctx context.Context, books []Book) error
+}
+
+// ExportContext exports books with f, passing ctx on when f supports cancellation.
+func ExportContext(ctx context.Context, f Format, books []Book) error {
+	if ce, ok := f.(ContextExporter); ok {
+		return ce.ExportContext(ctx, books)
+	}
+	return f.Export(books)
+}
+
+// openOutput creates path, or opens it for appending when appendMode is set.
+func openOutput(path string, appendMode bool) (*os.File, error) {
+	if appendMode {
+		return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	}
+	return os.Create(path)
+}
+
 // FormatFactory holds metadata + builder for a format implementation.
 type FormatFactory struct {
 	Name  string
@@ -30,7 +129,12 @@ type FormatFactory struct {
 type FlagProvider interface{ CLIFlag() any }
 
 // FlagValueResolver abstracts fetching CLI flag values (allows easier testing).
-type FlagValueResolver interface{ String(name string) string }
+type FlagValueResolver interface {
+	String(name string) string
+	StringSlice(name string) []string // values of a repeatable flag
+	Int(name string) int
+	Bool(name string) bool
+}
 
 var formatRegistry = map[string]*FormatFactory{}
 
@@ -43,11 +147,12 @@ func GetFormatFactory(name string) (*FormatFactory, bool) {
 	return f, ok
 }
 
-// ListFormatNames returns registered format names.
+// ListFormatNames returns registered format names, sorted so help text and errors are stable.
 func ListFormatNames() []string {
 	names := make([]string, 0, len(formatRegistry))
 	for n := range formatRegistry {
 		names = append(names, n)
 	}
+	sort.Strings(names)
 	return names
 }
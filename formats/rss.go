@@ -0,0 +1,226 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// RSSFormat writes an Atom feed with one entry per highlight, newest first, so new
+// highlights show up in a feed reader.
+type RSSFormat struct {
+	File    string // output path; "-" writes to stdout
+	written []Output
+}
+
+func (r *RSSFormat) Name() string { return "rss" }
+
+// WritesStdout reports whether the feed goes to stdout.
+func (r *RSSFormat) WritesStdout() bool { return r.File == "-" }
+
+// Outputs lists the feed file once written (nothing for stdout).
+func (r *RSSFormat) Outputs() []Output { return r.written }
+
+type atomFeed struct {
+	XMLName   xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Author    atomPerson  `xml:"author"`
+	Generator string      `xml:"generator"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  *atomPerson `xml:"author,omitempty"`
+	Content atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+func (r *RSSFormat) Export(books []Book) error {
+	var w io.Writer = os.Stdout
+	var file *os.File
+	if !r.WritesStdout() {
+		f, err := os.Create(r.File)
+		if err != nil {
+			return fmt.Errorf("create file %s: %w", r.File, err)
+		}
+		file, w = f, f
+	}
+	err := writeFeed(w, atomFeedOf(books, time.Now()))
+	if file != nil {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("write feed: %w", err)
+	}
+	if !r.WritesStdout() {
+		r.written = append(r.written, Output{Path: r.File})
+	}
+	return nil
+}
+
+// writeFeed writes the XML declaration and the indented feed.
+func writeFeed(w io.Writer, feed atomFeed) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ValidateOutput decodes the written feed again and checks that the document is an Atom
+// feed with nothing but whitespace after it.
+func (r *RSSFormat) ValidateOutput() error {
+	if r.WritesStdout() {
+		return nil
+	}
+	data, err := os.ReadFile(r.File)
+	if err != nil {
+		return fmt.Errorf("read feed %s: %w", r.File, err)
+	}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var feed atomFeed
+	if err := dec.Decode(&feed); err != nil {
+		return fmt.Errorf("%s: %w", r.File, err)
+	}
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", r.File, err)
+		}
+		if text, ok := tok.(xml.CharData); !ok || len(bytes.TrimSpace(text)) > 0 {
+			return fmt.Errorf("%s: unexpected content after the feed", r.File)
+		}
+	}
+}
+
+// atomFeedOf builds the feed. Entries are sorted newest first; the feed's updated time
+// is the newest highlight's (now if none has a parsed date), and highlights without a
+// parsed date use it too, after the dated ones.
+func atomFeedOf(books []Book, now time.Time) atomFeed {
+	type dated struct {
+		book Book
+		h    Highlight
+	}
+	var all []dated
+	var newest time.Time
+	for _, b := range books {
+		for _, h := range b.Highlights {
+			if strings.TrimSpace(h.Text) == "" {
+				continue
+			}
+			all = append(all, dated{b, h})
+			if h.Time.After(newest) {
+				newest = h.Time
+			}
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		ti, tj := all[i].h.Time, all[j].h.Time
+		if ti.IsZero() || tj.IsZero() {
+			return !ti.IsZero() && tj.IsZero()
+		}
+		return ti.After(tj)
+	})
+	if newest.IsZero() {
+		newest = now
+	}
+	feed := atomFeed{
+		ID:        "urn:uuid:5d1b4f0e-7c1a-5a4e-9d43-6b6f626f2d68",
+		Title:     "Kobo highlights",
+		Updated:   newest.UTC().Format(time.RFC3339),
+		Author:    atomPerson{Name: "kobo-highlights"},
+		Generator: "kobo-highlights",
+	}
+	for _, d := range all {
+		updated := d.h.Time
+		if updated.IsZero() {
+			updated = newest
+		}
+		title := bookHeading(d.book)
+		if d.h.Chapter != "" {
+			title += " – " + d.h.Chapter
+		}
+		e := atomEntry{
+			ID:      atomEntryID(HighlightHash(d.book, d.h)),
+			Title:   title,
+			Updated: updated.UTC().Format(time.RFC3339),
+			Content: atomContent{Type: "html", Body: atomEntryHTML(d.h)},
+		}
+		if d.book.Author != "" {
+			e.Author = &atomPerson{Name: d.book.Author}
+		}
+		feed.Entries = append(feed.Entries, e)
+	}
+	return feed
+}
+
+// atomEntryHTML is the entry content: the highlight as a blockquote and its note, if
+// any, as a paragraph (escaped again by the XML encoder, as type="html" requires).
+func atomEntryHTML(h Highlight) string {
+	s := "<blockquote>" + string(htmlQuote(h)) + "</blockquote>"
+	if note := strings.TrimSpace(h.Note); note != "" {
+		s += "<p><strong>Note:</strong> " + template.HTMLEscapeString(note) + "</p>"
+	}
+	return s
+}
+
+// atomEntryID formats a highlight's content hash as a version 5 style UUID URN, so an
+// entry keeps its ID across exports and readers do not show it as new again.
+func atomEntryID(hash string) string {
+	b := []byte(hash[:32])
+	b[12] = '5'                                                    // version
+	b[16] = "89ab"[strings.IndexByte("0123456789abcdef", b[16])%4] // variant
+	return fmt.Sprintf("urn:uuid:%s-%s-%s-%s-%s", b[0:8], b[8:12], b[12:16], b[16:20], b[20:32])
+}
+
+// registration
+type rssFileFlag struct{}
+
+func (rssFileFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "rss-file", Usage: "Output file for the Atom feed (- for stdout; required when --format rss)"}
+}
+
+func init() {
+	RegisterFormat(&FormatFactory{
+		Name:  "rss",
+		Flags: []FlagProvider{rssFileFlag{}},
+		Build: func(r FlagValueResolver) (Format, error) {
+			file := strings.TrimSpace(r.String("rss-file"))
+			if file == "" {
+				return nil, fmt.Errorf("--rss-file required for format rss")
+			}
+			return &RSSFormat{File: file}, nil
+		},
+	})
+}
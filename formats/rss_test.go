@@ -0,0 +1,31 @@
+package formats
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRSSValidateOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feed.xml")
+	r := &RSSFormat{File: path}
+	books := []Book{{Title: "Dune <1>", Author: "Frank Herbert", Highlights: []Highlight{{Text: "Fear & the mind-killer.", Date: "2023-05-01T10:34:56.000"}}}}
+	if err := r.Export(books); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ValidateOutput(); err != nil {
+		t.Fatalf("ValidateOutput rejected the written feed: %v", err)
+	}
+	for name, doc := range map[string]string{
+		"unclosed element": `<?xml version="1.0"?><feed xmlns="http://www.w3.org/2005/Atom"><title>x</feed>`,
+		"not a feed":       `<?xml version="1.0"?><rss><channel/></rss>`,
+		"trailing content": `<feed xmlns="http://www.w3.org/2005/Atom"></feed><feed/>`,
+	} {
+		if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.ValidateOutput(); err == nil {
+			t.Errorf("%s: ValidateOutput accepted %s", name, doc)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package formats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// textWrapWidth is the column at which highlight text is wrapped.
+const textWrapWidth = 80
+
+// TextFormat writes plain text in the console preview layout, untruncated: one combined
+// file (File, "-" for stdout) or one .txt per book (Dir).
+type TextFormat struct {
+	File       string
+	Dir        string
+	appendMode bool
+	written    []Output
+	out        *bufio.Writer     // combined output while a stream is open
+	file       io.Closer         // file behind out (nil for stdout)
+	names      map[string]string // book key -> file name assigned in this process (Dir)
+	usedNames  map[string]bool   // lower-cased file names already assigned (Dir)
+}
+
+func (t *TextFormat) Name() string { return "text" }
+
+// WritesStdout reports whether the text goes to stdout.
+func (t *TextFormat) WritesStdout() bool { return t.Dir == "" && t.File == "-" }
+
+// Outputs lists the files written so far.
+func (t *TextFormat) Outputs() []Output { return t.written }
+
+// EnableAppend makes later exports append to the existing files.
+func (t *TextFormat) EnableAppend() { t.appendMode = true }
+
+func (t *TextFormat) Export(books []Book) error {
+	if err := t.StartStream(); err != nil {
+		return err
+	}
+	for _, b := range books {
+		if err := t.StreamBook(b); err != nil {
+			t.EndStream()
+			return err
+		}
+	}
+	return t.EndStream()
+}
+
+// StartStream opens the combined file (or stdout), or creates Dir.
+func (t *TextFormat) StartStream() error {
+	if t.Dir != "" {
+		if err := os.MkdirAll(t.Dir, 0o755); err != nil {
+			return fmt.Errorf("create dir: %w", err)
+		}
+		return nil
+	}
+	if t.File == "" {
+		return fmt.Errorf("text format: empty file path")
+	}
+	if t.WritesStdout() {
+		t.out = bufio.NewWriter(os.Stdout)
+		return nil
+	}
+	f, err := createText(t.File, t.appendMode)
+	if err != nil {
+		return fmt.Errorf("create file %s: %w", t.File, err)
+	}
+	t.file, t.out = f, bufio.NewWriter(f)
+	return nil
+}
+
+// StreamBook writes one book to the combined output, or its own file with Dir.
+func (t *TextFormat) StreamBook(b Book) error {
+	if t.Dir != "" {
+		return t.exportDir([]Book{b})
+	}
+	writeTextBook(t.out, b)
+	return nil
+}
+
+// EndStream flushes and closes the combined output.
+func (t *TextFormat) EndStream() error {
+	if t.out == nil {
+		return nil
+	}
+	out, file := t.out, t.file
+	t.out, t.file = nil, nil
+	if file == nil {
+		if err := out.Flush(); err != nil {
+			return fmt.Errorf("write stdout: %w", err)
+		}
+		return nil
+	}
+	if err := out.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("write file %s: %w", t.File, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("close file %s: %w", t.File, err)
+	}
+	t.written = append(t.written, Output{Path: t.File})
+	return nil
+}
+
+// exportDir writes one .txt per book into Dir (created by StartStream).
+func (t *TextFormat) exportDir(books []Book) error {
+	for _, b := range books {
+		path := filepath.Join(t.Dir, t.bookFilename(b)+".txt")
+		f, err := createText(path, t.appendMode)
+		if err != nil {
+			return fmt.Errorf("create file %s: %w", path, err)
+		}
+		w := bufio.NewWriter(f)
+		writeTextBook(w, b)
+		if err := w.Flush(); err != nil {
+			f.Close()
+			return fmt.Errorf("write file %s: %w", path, err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("close file %s: %w", path, err)
+		}
+		t.written = append(t.written, Output{Path: path})
+	}
+	return nil
+}
+
+// bookFilename returns the sanitized Title[-Author] file name for a book, without
+// extension, with a -2, -3… suffix when another book already got that name. A book
+// keeps its name across exports, so appends go to the same file.
+func (t *TextFormat) bookFilename(b Book) string {
+	key := b.Title + "\x00" + b.Author
+	if name, ok := t.names[key]; ok {
+		return name
+	}
+	if t.names == nil {
+		t.names, t.usedNames = map[string]string{}, map[string]bool{}
+	}
+	base := sanitizeFilename(b.Title)
+	if b.Author != "" {
+		base = sanitizeFilename(b.Title + "-" + b.Author)
+	}
+	name := base
+	for i := 2; t.usedNames[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	t.names[key] = name
+	t.usedNames[strings.ToLower(name)] = true
+	return name
+}
+
+// writeTextBook writes a separator, the title/author header and numbered, wrapped highlights.
+func writeTextBook(w io.Writer, b Book) {
+	fmt.Fprintln(w, "====================")
+	if b.Author != "" {
+		fmt.Fprintf(w, "%s (%s)\n", b.Title, b.Author)
+	} else {
+		fmt.Fprintf(w, "%s\n", b.Title)
+	}
+	for i, h := range b.Highlights {
+		prefix := fmt.Sprintf("  %2d. ", i+1)
+		lines := wrapText(strings.TrimSpace(h.Text), textWrapWidth-len(prefix))
+		for j, l := range lines {
+			if j == 0 {
+				fmt.Fprintf(w, "%s%s\n", prefix, l)
+			} else {
+				fmt.Fprintf(w, "%s%s\n", strings.Repeat(" ", len(prefix)), l)
+			}
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// wrapText splits s into lines of at most width runes, breaking on whitespace.
+// Words longer than width are kept whole on their own line.
+func wrapText(s string, width int) []string {
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		return []string{""}
+	}
+	var lines []string
+	line := words[0]
+	lineLen := len([]rune(line))
+	for _, word := range words[1:] {
+		n := len([]rune(word))
+		if lineLen+1+n > width {
+			lines = append(lines, line)
+			line, lineLen = word, n
+			continue
+		}
+		line += " " + word
+		lineLen += 1 + n
+	}
+	return append(lines, line)
+}
+
+// registration
+type textFileFlag struct{}
+
+func (textFileFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "text-file", Usage: "Output file for plain text (one file for all books; - for stdout)"}
+}
+
+type textDirFlag struct{}
+
+func (textDirFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "text-dir", Usage: "Directory for plain text output (one .txt per book)"}
+}
+
+func init() {
+	RegisterFormat(&FormatFactory{
+		Name:  "text",
+		Flags: []FlagProvider{textFileFlag{}, textDirFlag{}},
+		Build: func(r FlagValueResolver) (Format, error) {
+			file := strings.TrimSpace(r.String("text-file"))
+			dir := strings.TrimSpace(r.String("text-dir"))
+			if (file == "") == (dir == "") {
+				return nil, fmt.Errorf("exactly one of --text-file or --text-dir required for format text")
+			}
+			return &TextFormat{File: file, Dir: dir}, nil
+		},
+	})
+}
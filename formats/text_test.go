@@ -0,0 +1,37 @@
+package formats
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTextDirCollidingTitlesGetSeparateFiles(t *testing.T) {
+	dir := t.TempDir()
+	tf := &TextFormat{Dir: dir}
+	books := []Book{
+		{Title: "Book One?", Highlights: []Highlight{{Text: "from the question edition"}}},
+		{Title: "Book One", Highlights: []Highlight{{Text: "from the plain edition"}}},
+		{Title: "Book: One", Highlights: []Highlight{{Text: "from the colon edition"}}},
+	}
+	if err := tf.Export(books); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"Book-One.txt":   "from the question edition",
+		"Book-One-2.txt": "from the plain edition",
+		"Book--One.txt":  "from the colon edition",
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("missing %s: %v", name, err)
+		}
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s does not hold its own book:\n%s", name, data)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 3 {
+		t.Errorf("wrote %d files, want 3", len(entries))
+	}
+}
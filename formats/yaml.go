@@ -0,0 +1,172 @@
+package formats
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLFormat writes all books as one YAML document with a top-level books: list.
+type YAMLFormat struct {
+	File    string // output path; "-" writes to stdout
+	written []Output
+}
+
+func (y *YAMLFormat) Name() string { return "yaml" }
+
+// WritesStdout reports whether the YAML goes to stdout.
+func (y *YAMLFormat) WritesStdout() bool { return y.File == "-" }
+
+// Outputs lists the YAML file once written (nothing for stdout).
+func (y *YAMLFormat) Outputs() []Output { return y.written }
+
+func (y *YAMLFormat) Export(books []Book) error {
+	var out io.Writer = os.Stdout
+	var file *os.File
+	if !y.WritesStdout() {
+		f, err := os.Create(y.File)
+		if err != nil {
+			return fmt.Errorf("create file %s: %w", y.File, err)
+		}
+		file, out = f, f
+	}
+	err := writeYAML(out, books)
+	if file != nil {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("write yaml: %w", err)
+	}
+	if !y.WritesStdout() {
+		y.written = append(y.written, Output{Path: y.File})
+	}
+	return nil
+}
+
+// writeYAML encodes books as a node tree, which keeps the key order and lets text
+// choose its own scalar style.
+func writeYAML(w io.Writer, books []Book) error {
+	list := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, b := range books {
+		book := yamlMap("title", yamlText(b.Title), "author", yamlText(b.Author))
+		for _, kv := range [][2]string{{"series", b.Series}, {"series_number", b.SeriesNumber}, {"language", b.Language}, {"isbn", b.ISBN}, {"publisher", b.Publisher}} {
+			if kv[1] != "" {
+				yamlAdd(book, kv[0], yamlText(kv[1]))
+			}
+		}
+		yamlAdd(book, "progress", yamlInt(b.Progress))
+		yamlAdd(book, "words", yamlInt(b.Words))
+		yamlAdd(book, "characters", yamlInt(b.Characters))
+		highlights := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, h := range b.Highlights {
+			hl := yamlMap("text", yamlText(h.Text))
+			for _, kv := range [][2]string{{"note", h.Note}, {"chapter", h.Chapter}, {"color", h.Color}, {"date", h.Date}} {
+				if kv[1] != "" {
+					yamlAdd(hl, kv[0], yamlText(kv[1]))
+				}
+			}
+			highlights.Content = append(highlights.Content, hl)
+		}
+		if len(highlights.Content) == 0 {
+			highlights.Style = yaml.FlowStyle
+		}
+		yamlAdd(book, "highlights", highlights)
+		list.Content = append(list.Content, book)
+	}
+	if len(list.Content) == 0 {
+		list.Style = yaml.FlowStyle
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{yamlMap("books", list)}}); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// yamlMap returns a mapping node of the given key, value pairs.
+func yamlMap(kvs ...any) *yaml.Node {
+	m := &yaml.Node{Kind: yaml.MappingNode}
+	for i := 0; i < len(kvs); i += 2 {
+		yamlAdd(m, kvs[i].(string), kvs[i+1].(*yaml.Node))
+	}
+	return m
+}
+
+func yamlAdd(m *yaml.Node, key string, value *yaml.Node) {
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// yamlText is a string scalar: a literal block (|) when it spans several lines, so
+// multiline highlights stay readable, else double-quoted. The encoder falls back to
+// double quotes for text a block scalar cannot hold.
+func yamlText(s string) *yaml.Node {
+	style := yaml.DoubleQuotedStyle
+	if strings.Contains(s, "\n") {
+		style = yaml.LiteralStyle
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s, Style: style}
+}
+
+// yamlString renders s as a double-quoted YAML scalar for a single key: value line,
+// as in the markdown frontmatter; line breaks become \n escapes.
+func yamlString(s string) string {
+	out, err := yaml.Marshal(&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s, Style: yaml.DoubleQuotedStyle})
+	if err != nil {
+		return strconv.Quote(s) // not reached: a string scalar always encodes
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
+
+func yamlInt(n int) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(n)}
+}
+
+// ValidateOutput parses the written file again and checks it holds a books list.
+func (y *YAMLFormat) ValidateOutput() error {
+	if y.WritesStdout() {
+		return nil
+	}
+	data, err := os.ReadFile(y.File)
+	if err != nil {
+		return fmt.Errorf("read yaml %s: %w", y.File, err)
+	}
+	var doc struct {
+		Books *[]map[string]any `yaml:"books"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("%s: %w", y.File, err)
+	}
+	if doc.Books == nil {
+		return fmt.Errorf("%s: no books list", y.File)
+	}
+	return nil
+}
+
+// registration
+type yamlFileFlag struct{}
+
+func (yamlFileFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "yaml-file", Usage: "Output file for YAML (- for stdout; required when --format yaml)"}
+}
+
+func init() {
+	RegisterFormat(&FormatFactory{
+		Name:  "yaml",
+		Flags: []FlagProvider{yamlFileFlag{}},
+		Build: func(r FlagValueResolver) (Format, error) {
+			file := strings.TrimSpace(r.String("yaml-file"))
+			if file == "" {
+				return nil, fmt.Errorf("--yaml-file required for format yaml")
+			}
+			return &YAMLFormat{File: file}, nil
+		},
+	})
+}
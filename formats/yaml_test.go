@@ -0,0 +1,69 @@
+package formats
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestYAMLExportRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.yaml")
+	books := []Book{{
+		Title:  `Dune: "Book" #1`,
+		Author: "Frank Herbert",
+		Series: "- Dune",
+		Highlights: []Highlight{
+			{Text: "Fear is the mind-killer.\nFear is the little-death.", Note: "key: value", Chapter: "Chapter 1"},
+			{Text: "  indented\nsecond line\n\n"},
+			{Text: "carriage\r\nreturn"},
+		},
+	}, {Title: "Empty"}}
+	y := &YAMLFormat{File: path}
+	if err := y.Export(books); err != nil {
+		t.Fatal(err)
+	}
+	if err := y.ValidateOutput(); err != nil {
+		t.Fatalf("ValidateOutput: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "      - text: |-\n          Fear is the mind-killer.\n          Fear is the little-death.\n") {
+		t.Errorf("multiline text is not a literal block scalar:\n%s", data)
+	}
+	var doc struct {
+		Books []struct {
+			Title      string `yaml:"title"`
+			Series     string `yaml:"series"`
+			Highlights []struct {
+				Text string `yaml:"text"`
+				Note string `yaml:"note"`
+			} `yaml:"highlights"`
+		} `yaml:"books"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("output does not parse: %v\n%s", err, data)
+	}
+	if len(doc.Books) != 2 || doc.Books[0].Title != books[0].Title || doc.Books[0].Series != books[0].Series {
+		t.Fatalf("books = %+v", doc.Books)
+	}
+	for i, h := range doc.Books[0].Highlights {
+		if want := books[0].Highlights[i]; h.Text != want.Text || h.Note != want.Note {
+			t.Errorf("highlight %d = %q / %q, want %q / %q", i, h.Text, h.Note, want.Text, want.Note)
+		}
+	}
+}
+
+func TestYAMLValidateOutputRejectsBrokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.yaml")
+	if err := os.WriteFile(path, []byte("books:\n  - title: \"unterminated\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&YAMLFormat{File: path}).ValidateOutput(); err == nil {
+		t.Fatal("ValidateOutput accepted a broken document")
+	}
+}
@@ -5,6 +5,8 @@ go 1.24.3
 require (
 	github.com/mattn/go-sqlite3 v1.14.28
 	github.com/urfave/cli/v2 v2.27.6
+	golang.org/x/text v0.30.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
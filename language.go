@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/ozmodiar/kobo-highlights/formats"
+)
+
+// stopwords lists very common function words per language; counting them is a cheap
+// detector that works on highlight-length text without a model.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "a", "in", "is", "that", "it", "was", "for", "with", "as", "his", "her", "not", "be", "but", "you", "i"},
+	"fr": {"le", "la", "les", "de", "des", "et", "un", "une", "est", "que", "qu", "pas", "pour", "dans", "il", "elle", "ne", "avec", "au", "du"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "zu", "den", "mit", "sich", "auf", "ich", "es", "dem", "auch", "von", "im", "wie"},
+	"es": {"el", "la", "los", "las", "de", "y", "que", "en", "un", "una", "es", "por", "con", "no", "se", "para", "del", "lo", "al", "su"},
+	"it": {"il", "lo", "la", "gli", "le", "di", "e", "che", "un", "una", "è", "non", "per", "con", "del", "della", "si", "ma", "sono", "nel"},
+	"nl": {"de", "het", "een", "en", "van", "is", "dat", "niet", "te", "op", "ik", "zijn", "met", "voor", "die", "er", "maar", "ook", "als", "naar"},
+	"pt": {"o", "a", "os", "as", "de", "e", "que", "em", "um", "uma", "não", "do", "da", "para", "com", "se", "por", "mais", "ao", "seu"},
+}
+
+var stopwordSets = func() map[string]map[string]bool {
+	sets := map[string]map[string]bool{}
+	for lang, words := range stopwords {
+		sets[lang] = map[string]bool{}
+		for _, w := range words {
+			sets[lang][w] = true
+		}
+	}
+	return sets
+}()
+
+// detectLanguage returns the language whose stopwords occur most often in text, or ""
+// when no language clearly wins (too short, or a tie).
+func detectLanguage(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) })
+	best, bestScore, tie := "", 0, false
+	for lang, set := range stopwordSets {
+		score := 0
+		for _, w := range words {
+			if set[w] {
+				score++
+			}
+		}
+		switch {
+		case score > bestScore:
+			best, bestScore, tie = lang, score, false
+		case score == bestScore && score > 0:
+			tie = true
+		}
+	}
+	if tie || bestScore == 0 {
+		return ""
+	}
+	return best
+}
+
+// primaryLanguage reduces a language tag to its lower-case primary subtag ("en-US" -> "en").
+func primaryLanguage(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	return tag
+}
+
+// filterByLanguage keeps highlights in lang. By default the book's language metadata
+// decides for all its highlights; with detect each highlight is classified on its own,
+// falling back to the book language when detection is inconclusive. Books left empty
+// are dropped.
+func filterByLanguage(books []formats.Book, lang string, detect bool) []formats.Book {
+	lang = primaryLanguage(lang)
+	out := make([]formats.Book, 0, len(books))
+	for _, b := range books {
+		bookLang := primaryLanguage(b.Language)
+		if !detect {
+			if bookLang == lang {
+				out = append(out, b)
+			}
+			continue
+		}
+		kept := make([]formats.Highlight, 0, len(b.Highlights))
+		for _, h := range b.Highlights {
+			got := detectLanguage(h.Text)
+			if got == "" {
+				got = bookLang
+			}
+			if got == lang {
+				kept = append(kept, h)
+			}
+		}
+		if len(kept) > 0 {
+			b.Highlights = kept
+			out = append(out, b)
+		}
+	}
+	return out
+}
@@ -1,20 +1,24 @@
 package main
 
 import (
-	"database/sql"
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"sort"
 	"strings"
+	"syscall"
+	"time"
+	"unicode"
 	"unicode/utf8"
 
-	_ "github.com/mattn/go-sqlite3"
 	"github.com/urfave/cli/v2"
+	"golang.org/x/text/language"
 
 	"github.com/ozmodiar/kobo-highlights/formats"
+	"github.com/ozmodiar/kobo-highlights/sources"
 )
 
 // Console preview helper length.
@@ -25,33 +29,87 @@ type cliResolver struct{ ctx *cli.Context }
 
 func (r cliResolver) String(name string) string { return r.ctx.String(name) }
 
+func (r cliResolver) StringSlice(name string) []string { return r.ctx.StringSlice(name) }
+
+func (r cliResolver) Int(name string) int { return r.ctx.Int(name) }
+
+func (r cliResolver) Bool(name string) bool { return r.ctx.Bool(name) }
+
 func main() {
 	// Build dynamic exporter flags
 	exporterNames := formats.ListFormatNames()
+	sourceNames := sources.ListSourceNames()
 	baseFlags := []cli.Flag{
-		&cli.StringFlag{Name: "kobo-db", Usage: "Path to the KoboReader.sqlite file", Required: true},
-		&cli.IntFlag{Name: "limit", Usage: "Maximum number of highlights to fetch (omit or 0 = all)"},
+		&cli.StringFlag{Name: "config", Usage: "TOML file of flag defaults (keys are flag names; command-line flags and environment variables take precedence)"},
+		&cli.StringFlag{Name: "source", Value: "kobo", Usage: "Highlight source (one of: " + strings.Join(sourceNames, ", ") + ")"},
+		&cli.IntFlag{Name: "limit", Usage: "Stop adding whole books once this many highlights are included (omit or 0 = all)"},
+		&cli.IntFlag{Name: "limit-per-book", Usage: "Keep only the first N highlights of each book, in --sort order (omit or 0 = all)"},
+		&cli.BoolFlag{Name: "limit-strict", Usage: "Apply --limit as an exact row limit in the query (may cut the last book short)"},
 		&cli.BoolFlag{Name: "list-formats", Usage: "List available output formats and exit"},
-		&cli.StringFlag{Name: "format", Usage: "Output format (one of: " + strings.Join(exporterNames, ", ") + ")"},
+		&cli.StringSliceFlag{Name: "format", Usage: "Output format (one of: " + strings.Join(exporterNames, ", ") + "); repeat or separate with commas to write several from one read"},
+		&cli.BoolFlag{Name: "stats", Usage: "Print a summary (books, highlights, average per book, most-highlighted book) to stderr after the export"},
+		&cli.BoolFlag{Name: "stream", Usage: "Write each book as soon as it is read instead of loading the whole library first (text and jsonl formats, single database)"},
+		&cli.BoolFlag{Name: "dry-run", Usage: "Show the books and highlight counts that would be exported without writing files or calling any API"},
+		&cli.BoolFlag{Name: "yes", Usage: "Assume yes for confirmation prompts (non-interactive runs)"},
+		&cli.StringFlag{Name: "output-encoding", Value: formats.EncodingUTF8, Usage: "Encoding of markdown, text, anki and instapaper files: utf8, utf8-bom or utf16le (for legacy Windows tools)"},
+		&cli.BoolFlag{Name: "verbose", Usage: "Log each Notion request and each file written"},
+		&cli.BoolFlag{Name: "quiet", Usage: "Only print warnings and errors (no console preview or progress messages)"},
 		&cli.BoolFlag{Name: "debug", Usage: "Enable verbose debug logging (same as setting KOBO_DEBUG=1)"},
+		&cli.BoolFlag{Name: "only-finished", Usage: "Only export books whose reading progress is at or above --finished-threshold"},
+		&cli.BoolFlag{Name: "only-in-progress", Usage: "Only export books that are started but below --finished-threshold"},
+		&cli.IntFlag{Name: "finished-threshold", Value: 95, Usage: "Percent read at which a book counts as finished"},
+		&cli.IntFlag{Name: "min-progress", Usage: "Only export books read at least this many percent (e.g. 10 to skip samples and books barely started)"},
+		&cli.StringFlag{Name: "sort", Usage: "Comma-separated orders: within-book=position (reading order; default) or within-book=date (default when merging several --kobo-db); books=title (default), author, recent or count (most highlights first)"},
+		&cli.StringFlag{Name: "color", Usage: "Only export highlights of this color: yellow, red, green, blue or pink"},
+		&cli.BoolFlag{Name: "notes-only", Usage: "Only export highlights that have a note (books left without highlights are dropped)"},
+		&cli.IntFlag{Name: "min-length", Usage: "Drop highlights shorter than this many characters (after trimming whitespace)"},
+		&cli.BoolFlag{Name: "clean-artifacts", Usage: "Experimental: strip page numbers and running headers caught in highlights"},
+		&cli.StringFlag{Name: "locale", Usage: "Language whose rules order book titles, as a BCP 47 tag (e.g. de, sv, fr-CA; default: Unicode root order)"},
+		&cli.StringFlag{Name: "timezone", Usage: "IANA time zone the device clock was set to, used to interpret highlight dates (default: system local)"},
+		&cli.StringFlag{Name: "title", Usage: "Only export books whose title contains this text (case-insensitive)"},
+		&cli.StringFlag{Name: "author", Usage: "Only export books whose author contains this text (case-insensitive)"},
+		&cli.StringSliceFlag{Name: "exclude-book", Usage: "Skip books with this title, matched case-insensitively and whole; * and ? are wildcards (repeat or separate with commas, e.g. dictionaries or the user guide)"},
+		&cli.StringSliceFlag{Name: "exclude-author", Usage: "Skip books by this author, matched like --exclude-book (repeatable)"},
+		&cli.StringFlag{Name: "since", Usage: "Only export highlights made at or after this time (RFC3339 or YYYY-MM-DD)"},
+		&cli.StringFlag{Name: "until", Usage: "Only export highlights made before this time; a YYYY-MM-DD date includes that day"},
+		&cli.BoolFlag{Name: "include-hidden", Usage: "Also export highlights deleted on the device (Kobo keeps them as hidden rows)"},
+		&cli.BoolFlag{Name: "preserve-formatting", Usage: "Keep bold/italic emphasis captured by the device (markdown and Notion)"},
+		&cli.StringFlag{Name: "highlight-lang", Usage: "Only export highlights in this language (e.g. en, fr), judged by the book's language metadata"},
+		&cli.BoolFlag{Name: "detect-lang", Usage: "With --highlight-lang, detect the language of each highlight instead (slower, better for multilingual books)"},
+		&cli.StringFlag{Name: "search", Usage: "Only export highlights containing this text (case-insensitive)"},
+		&cli.BoolFlag{Name: "search-regex", Usage: "Treat --search as a regular expression (case-insensitive)"},
+		&cli.IntFlag{Name: "context", Usage: "With --search, also include N highlights before and after each match"},
+		&cli.BoolFlag{Name: "inline-notes", Usage: "Append each highlight's note to its text (for formats without a separate note field)"},
+		&cli.StringFlag{Name: "inline-notes-separator", Value: formats.DefaultNoteSeparator, Usage: "Text placed between the quoted highlight and its note with --inline-notes"},
+		&cli.StringFlag{Name: "diff", Usage: "Previous JSON export; only highlights not present in it are exported"},
+		&cli.StringFlag{Name: "snapshot-dir", Usage: "Directory of timestamped highlight hash snapshots; export only highlights added since the last one and report removals"},
+		&cli.BoolFlag{Name: "watch", Usage: "After the export, keep polling the database and export new highlights as they appear (until interrupted)"},
+		&cli.DurationFlag{Name: "watch-interval", Value: 30 * time.Second, Usage: "Polling interval for --watch"},
+		&cli.StringFlag{Name: "manifest", Usage: "Write a JSON manifest of the files and Notion pages produced by the run"},
+		&cli.BoolFlag{Name: "validate-output", Usage: "Re-read written files and fail if they do not parse (formats with a strict syntax)"},
+		&cli.StringFlag{Name: "dedupe-db", Usage: "File of exported highlight hashes; skip highlights already recorded and record new ones after a successful export"},
+	}
+	// Append source- and exporter-specific flags (all added; only used when chosen)
+	for _, name := range sourceNames {
+		if f, ok := sources.GetSourceFactory(name); ok {
+			baseFlags = appendProviderFlags(baseFlags, f.Flags)
+		}
 	}
-	// Append exporter-specific flags (all added; only used when chosen)
 	for _, name := range exporterNames {
 		if f, ok := formats.GetFormatFactory(name); ok {
-			for _, fp := range f.Flags {
-				if cf, ok2 := fp.CLIFlag().(cli.Flag); ok2 {
-					baseFlags = append(baseFlags, cf)
-				} else if cfp, ok3 := fp.CLIFlag().(*cli.StringFlag); ok3 { // handle pointer types
-					baseFlags = append(baseFlags, cfp)
-				}
-			}
+			baseFlags = appendProviderFlags(baseFlags, f.Flags)
 		}
 	}
 	app := &cli.App{
 		Name:  "kobo-highlights",
-		Usage: "Extract highlights from a KoboReader.sqlite database",
+		Usage: "Extract highlights from an e-reader database (KoboReader.sqlite by default)",
 		Flags: baseFlags,
 		Action: func(c *cli.Context) error {
+			if path := strings.TrimSpace(c.String("config")); path != "" {
+				if err := applyConfig(c, path); err != nil {
+					return err
+				}
+			}
 			if c.Bool("list-formats") {
 				fmt.Println("Available formats:")
 				for _, n := range exporterNames {
@@ -59,35 +117,251 @@ func main() {
 				}
 				return nil
 			}
-			dbPath := c.String("kobo-db")
+			switch {
+			case c.Bool("verbose") && c.Bool("quiet"):
+				return fmt.Errorf("--verbose and --quiet are mutually exclusive")
+			case c.Bool("verbose"):
+				formats.SetLogLevel(formats.LogVerbose)
+			case c.Bool("quiet"):
+				formats.SetLogLevel(formats.LogQuiet)
+			}
+			if err := formats.SetOutputEncoding(strings.ToLower(strings.TrimSpace(c.String("output-encoding")))); err != nil {
+				return fmt.Errorf("--output-encoding: %w", err)
+			}
 			limit := c.Int("limit")
-			format := strings.ToLower(strings.TrimSpace(c.String("format")))
-			if format == "" {
-				return fmt.Errorf("--format required unless --list-formats is used")
+			factories, err := formatFactories(c.StringSlice("format"), exporterNames)
+			if err != nil {
+				return err
 			}
-			factory, ok := formats.GetFormatFactory(format)
+			sourceName := strings.ToLower(strings.TrimSpace(c.String("source")))
+			sourceFactory, ok := sources.GetSourceFactory(sourceName)
 			if !ok {
-				return fmt.Errorf("unknown format '%s' (available: %s)", format, strings.Join(exporterNames, ", "))
+				return fmt.Errorf("unknown source '%s' (available: %s)", sourceName, strings.Join(sourceNames, ", "))
+			}
+			source, err := sourceFactory.Build(cliResolver{c})
+			if err != nil {
+				return err
 			}
 
-			debug := c.Bool("debug")
-			books, err := fetchBooks(dbPath, limit, debug)
+			progressMode, err := progressFilterMode(c.Bool("only-finished"), c.Bool("only-in-progress"))
+			if err != nil {
+				return err
+			}
+			var match func(string) bool
+			if q := c.String("search"); q != "" {
+				if match, err = searchMatcher(q, c.Bool("search-regex")); err != nil {
+					return err
+				}
+			}
+			color := strings.ToLower(strings.TrimSpace(c.String("color")))
+			if color != "" && !formats.IsHighlightColor(color) {
+				return fmt.Errorf("--color must be yellow, red, green, blue or pink")
+			}
+			if c.Int("min-length") < 0 {
+				return fmt.Errorf("--min-length must not be negative")
+			}
+			if c.Int("limit-per-book") < 0 {
+				return fmt.Errorf("--limit-per-book must not be negative")
+			}
+			if p := c.Int("min-progress"); p < 0 || p > 100 {
+				return fmt.Errorf("--min-progress must be between 0 and 100")
+			}
+			merger, ok := source.(sources.Merger)
+			sortSpec, err := parseSortFlag(c.String("sort"), ok && merger.Merges())
 			if err != nil {
 				return err
 			}
-			printConsolePreview(books)
 
-			// Resolver using cli.Context
-			resolver := cliResolver{c}
-			exporter, err := factory.Build(resolver)
+			debug := c.Bool("debug")
+			loc := time.Local
+			if tz := strings.TrimSpace(c.String("timezone")); tz != "" {
+				if loc, err = time.LoadLocation(tz); err != nil {
+					return fmt.Errorf("invalid --timezone: %w", err)
+				}
+			}
+			var locale language.Tag
+			if l := strings.TrimSpace(c.String("locale")); l != "" {
+				if locale, err = language.Parse(l); err != nil {
+					return fmt.Errorf("invalid --locale: %w", err)
+				}
+			}
+			var since, until time.Time
+			if v := c.String("since"); v != "" {
+				if since, err = parseDateBound("since", v, loc, false); err != nil {
+					return err
+				}
+			}
+			if v := c.String("until"); v != "" {
+				if until, err = parseDateBound("until", v, loc, true); err != nil {
+					return err
+				}
+			}
+			// Ctrl+C (or SIGTERM) cancels the read and any in-flight sync.
+			ctx, stop := signal.NotifyContext(c.Context, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			opts := sources.ReadOptions{Debug: debug, PreserveFormatting: c.Bool("preserve-formatting"), IncludeHidden: c.Bool("include-hidden"), Live: c.Bool("watch"), Location: loc, Context: ctx, Locale: locale}
+			if c.Bool("limit-strict") {
+				opts.Limit = limit
+			}
+			excluded := bookExcluder(c.StringSlice("exclude-book"), c.StringSlice("exclude-author"))
+			// filter applies the book and highlight filters and the order within each book.
+			filter := func(books []formats.Book) []formats.Book {
+				books = filterByBook(books, strings.TrimSpace(c.String("title")), strings.TrimSpace(c.String("author")))
+				books = excludeBooks(books, excluded)
+				books = filterByProgress(books, progressMode, c.Int("finished-threshold"))
+				books = filterByMinProgress(books, c.Int("min-progress"))
+				books = filterByDate(books, since, until)
+				if c.Bool("clean-artifacts") {
+					books = cleanArtifacts(books)
+				}
+				books = filterByMinLength(books, c.Int("min-length"))
+				books = filterByColor(books, color)
+				books = filterByNotes(books, c.Bool("notes-only"))
+				if lang := strings.TrimSpace(c.String("highlight-lang")); lang != "" {
+					books = filterByLanguage(books, lang, c.Bool("detect-lang"))
+				}
+				if match != nil {
+					books = searchHighlights(books, match, c.Int("context"))
+				}
+				return sortWithinBooks(books, sortSpec.within)
+			}
+			if c.Bool("stream") {
+				return streamExport(c, source, factories, opts, sortSpec, filter)
+			}
+			// read runs the source and the filters; --watch repeats it every poll.
+			read := func() ([]formats.Book, error) {
+				books, err := source.Read(opts)
+				if err != nil {
+					return nil, err
+				}
+				return sortBooks(filter(books), sortSpec.books, locale), nil
+			}
+			books, err := read()
+			if err != nil {
+				return err
+			}
+			watchSeen := map[string]bool{}
+			recordHashes(books, watchSeen)
+			if prev := c.String("diff"); prev != "" {
+				previous, err := formats.LoadJSON(prev)
+				if err != nil {
+					return err
+				}
+				seen := map[string]bool{}
+				recordHashes(previous, seen)
+				books = skipSeen(books, seen)
+			}
+			snapshotDir := c.String("snapshot-dir")
+			var previousSnapshot, currentSnapshot map[string]bool
+			if snapshotDir != "" {
+				var prevPath string
+				if prevPath, previousSnapshot, err = latestSnapshot(snapshotDir); err != nil {
+					return err
+				}
+				currentSnapshot = map[string]bool{}
+				recordHashes(books, currentSnapshot)
+				if prevPath != "" {
+					added, removed := diffSnapshot(previousSnapshot, currentSnapshot)
+					formats.Infof("snapshot: %d added, %d removed since %s", added, removed, filepath.Base(prevPath))
+				}
+				books = skipSeen(books, previousSnapshot)
+			}
+			dedupePath := c.String("dedupe-db")
+			var exported map[string]bool
+			if dedupePath != "" {
+				if exported, err = loadHashStore(dedupePath); err != nil {
+					return err
+				}
+				books = skipSeen(books, exported)
+			}
+			books = limitPerBook(books, c.Int("limit-per-book"))
+			if !c.Bool("limit-strict") {
+				books = limitBooks(books, limit)
+			}
+			books = formats.CountText(books)
+			if c.Bool("inline-notes") {
+				books = formats.InlineNotes(books, c.String("inline-notes-separator"))
+			}
+			exporters, err := buildExporters(factories, cliResolver{c})
 			if err != nil {
 				return err
 			}
-			if err := exporter.Export(books); err != nil {
+			watch := c.Bool("watch")
+			if watch {
+				for _, exporter := range exporters {
+					if _, ok := exporter.(formats.Appender); !ok {
+						return fmt.Errorf("format %s cannot be used with --watch (it does not support appending)", exporter.Name())
+					}
+					if exporter.Name() == "markdown" && c.Bool("markdown-split-chapters") {
+						return fmt.Errorf("--markdown-split-chapters cannot be used with --watch (chapter files cannot be appended to)")
+					}
+				}
+			}
+			if c.Bool("dry-run") {
+				printDryRun(exporters, books)
+				if c.Bool("stats") {
+					printStats(os.Stderr, books)
+				}
+				return nil
+			}
+			if !writesStdout(exporters) && formats.CurrentLogLevel() >= formats.LogNormal {
+				printConsolePreview(books)
+			}
+			run := &exportRun{exporters: exporters, validate: c.Bool("validate-output"), debug: debug, announce: true, logged: make([]int, len(exporters))}
+			if path := c.String("manifest"); path != "" {
+				run.manifest = &manifest{path: path}
+			}
+			if err := run.export(ctx, books); err != nil {
 				return err
 			}
-			fmt.Fprintf(os.Stderr, "%s export complete\n", exporter.Name())
-			return nil
+			if snapshotDir != "" {
+				// Keep what is still present from the last snapshot plus what was exported now;
+				// anything cut by --limit stays "added" for the next run.
+				next := map[string]bool{}
+				for h := range previousSnapshot {
+					if currentSnapshot[h] {
+						next[h] = true
+					}
+				}
+				recordHashes(books, next)
+				if _, err := saveSnapshot(snapshotDir, next, time.Now()); err != nil {
+					return err
+				}
+			}
+			if dedupePath != "" {
+				recordHashes(books, exported)
+				if err := saveHashStore(dedupePath, exported); err != nil {
+					return err
+				}
+			}
+			if c.Bool("stats") {
+				printStats(os.Stderr, books)
+			}
+			if !watch {
+				return nil
+			}
+			for _, exporter := range exporters {
+				exporter.(formats.Appender).EnableAppend()
+			}
+			run.manifest, run.validate, run.announce = nil, false, false
+			inlineSep := ""
+			if c.Bool("inline-notes") {
+				inlineSep = c.String("inline-notes-separator")
+			}
+			return watchLoop(ctx, c.Duration("watch-interval"), watchSeen, read, func(books []formats.Book) error {
+				books = formats.CountText(books)
+				if c.Bool("inline-notes") {
+					books = formats.InlineNotes(books, inlineSep)
+				}
+				if err := run.export(ctx, books); err != nil {
+					return err
+				}
+				if dedupePath != "" {
+					recordHashes(books, exported)
+					return saveHashStore(dedupePath, exported)
+				}
+				return nil
+			})
 		},
 	}
 
@@ -96,133 +370,180 @@ func main() {
 	}
 }
 
-// fetchBooks queries the DB and returns a slice of Book structs.
-func fetchBooks(dbPath string, limit int, debug bool) ([]formats.Book, error) {
-	// Ensure the file exists before opening; opening a non-existent file without read-only mode would create an empty DB.
-	if fi, err := os.Stat(dbPath); err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return nil, fmt.Errorf("database file not found: %s", dbPath)
-		}
-		return nil, fmt.Errorf("unable to stat database file: %w", err)
-	} else if fi.Size() < 1024 { // heuristic: Kobo DBs are typically several MB; extremely small likely wrong file
-		log.Printf("warning: database file is very small (%d bytes) – is this the correct KoboReader.sqlite?", fi.Size())
-		if debug {
-			log.Printf("DEBUG: db=%s size=%d bytes (suspiciously small)", dbPath, fi.Size())
-		}
-	} else if debug {
-		log.Printf("DEBUG: db=%s size=%d bytes", dbPath, fi.Size())
-	}
-
-	// Open in read-only mode to avoid accidental creation.
-	// Use a URI so we can set pragmas; no escaping needed for simple paths.
-	dsn := fmt.Sprintf("file:%s?mode=ro&_busy_timeout=5000", filepath.Clean(dbPath))
-	db, err := sql.Open("sqlite3", dsn)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
-	}
-	defer db.Close()
-
-	// Verify Bookmark table exists before running main query.
-	var tableName string
-	err = db.QueryRow(`SELECT name FROM sqlite_master WHERE type='table' AND name='Bookmark'`).Scan(&tableName)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "no such table") {
-			// List available tables for diagnostics.
-			rows, listErr := db.Query(`SELECT name FROM sqlite_master WHERE type='table' ORDER BY name`)
-			available := []string{}
-			if listErr == nil {
-				defer rows.Close()
-				for rows.Next() {
-					var n string
-					if scanErr := rows.Scan(&n); scanErr == nil {
-						available = append(available, n)
-					}
-				}
-			}
-			if debug {
-				log.Printf("DEBUG: Bookmark table missing; available tables: %s", strings.Join(available, ", "))
+// appendProviderFlags appends the cli flags behind a registry's flag providers.
+func appendProviderFlags(flags []cli.Flag, providers []formats.FlagProvider) []cli.Flag {
+	for _, fp := range providers {
+		if cf, ok := fp.CLIFlag().(cli.Flag); ok {
+			flags = append(flags, cf)
+		}
+	}
+	return flags
+}
+
+// printConsolePreview prints a deterministic summary to stdout.
+func printConsolePreview(books []formats.Book) {
+	for _, b := range books {
+		fmt.Println("====================")
+		if b.Author != "" {
+			fmt.Printf("%s (%s)\n", b.Title, b.Author)
+		} else {
+			fmt.Printf("%s\n", b.Title)
+		}
+		for i, h := range b.Highlights {
+			truncated := truncateClean(h.Text, previewLen)
+			fmt.Printf("  %2d. %s\n", i+1, truncated)
+		}
+		fmt.Println()
+	}
+}
+
+// formatFactories resolves the --format values (repeated or comma-separated) to their
+// factories, in the order given and without duplicates.
+func formatFactories(values, available []string) ([]*formats.FormatFactory, error) {
+	var factories []*formats.FormatFactory
+	seen := map[string]bool{}
+	for _, v := range values {
+		for _, name := range strings.Split(v, ",") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" || seen[name] {
+				continue
 			}
-			hint := "Ensure you passed the KoboReader.sqlite from the device (not BookReader.sqlite or another file)."
-			if len(available) == 0 {
-				hint += " No tables were found – the file might be empty or corrupted."
-			} else {
-				hint += " Available tables: " + strings.Join(available, ", ")
+			seen[name] = true
+			factory, ok := formats.GetFormatFactory(name)
+			if !ok {
+				return nil, fmt.Errorf("unknown format '%s' (available: %s)", name, strings.Join(available, ", "))
 			}
-			return nil, fmt.Errorf("required table 'Bookmark' not found. %s", hint)
+			factories = append(factories, factory)
 		}
-		return nil, fmt.Errorf("failed to inspect schema: %w", err)
 	}
-	if debug {
-		log.Printf("DEBUG: Found Bookmark table")
+	if len(factories) == 0 {
+		return nil, fmt.Errorf("--format required unless --list-formats is used")
 	}
+	return factories, nil
+}
 
-	baseQuery := `
-		SELECT c.Title, COALESCE(c.Attribution, ''), b.Text, b.DateCreated
-		FROM Bookmark b
-		JOIN content c ON c.ContentID = b.VolumeID
-		WHERE b.Text IS NOT NULL AND LENGTH(TRIM(b.Text)) > 0
-		ORDER BY c.Title ASC,
-		         b.ContentID ASC,
-		         CAST(SUBSTR(b.StartContainerPath, INSTR(b.StartContainerPath, '.')+1,
-		              INSTR(SUBSTR(b.StartContainerPath, INSTR(b.StartContainerPath, '.')+1), '.')-1) AS INTEGER) ASC,
-		         b.StartOffset ASC`
-
-	var rows *sql.Rows
-	if limit > 0 {
-		q := baseQuery + " LIMIT ?"
-		rows, err = db.Query(q, limit)
-	} else {
-		rows, err = db.Query(baseQuery)
+// buildExporters builds every selected format; at most one may write to stdout, since
+// their output would be interleaved.
+func buildExporters(factories []*formats.FormatFactory, r formats.FlagValueResolver) ([]formats.Format, error) {
+	exporters := make([]formats.Format, 0, len(factories))
+	var stdout []string
+	for _, f := range factories {
+		exporter, err := f.Build(r)
+		if err != nil {
+			return nil, err
+		}
+		if sw, ok := exporter.(formats.StdoutWriter); ok && sw.WritesStdout() {
+			stdout = append(stdout, exporter.Name())
+		}
+		exporters = append(exporters, exporter)
 	}
-	if err != nil {
-		return nil, fmt.Errorf("query failed: %w", err)
+	if len(stdout) > 1 {
+		return nil, fmt.Errorf("only one format can write to stdout (%s would); give the others a file", strings.Join(stdout, ", "))
 	}
-	defer rows.Close()
+	return exporters, nil
+}
+
+// writesStdout reports whether any of the exporters writes to stdout.
+func writesStdout(exporters []formats.Format) bool {
+	for _, e := range exporters {
+		if sw, ok := e.(formats.StdoutWriter); ok && sw.WritesStdout() {
+			return true
+		}
+	}
+	return false
+}
 
-	grouped := make(map[string]*formats.Book)
-	order := make([]string, 0)
-	for rows.Next() {
-		var title, author, text, date string
-		if err := rows.Scan(&title, &author, &text, &date); err != nil {
-			log.Printf("failed to scan row: %v", err)
+// exportRun exports to the selected formats, once or on every --watch poll.
+type exportRun struct {
+	exporters []formats.Format
+	manifest  *manifest // records each format's outcome when non-nil
+	validate  bool      // check each written output (--validate-output)
+	debug     bool
+	announce  bool  // log "<format> export complete" after each success
+	logged    []int // per exporter, the outputs logOutputs has seen
+}
+
+// export exports books with each format in turn. A failing format does not stop the
+// others; the errors are returned together, prefixed with the format name when there
+// are several.
+func (r *exportRun) export(ctx context.Context, books []formats.Book) error {
+	var errs []error
+	for i, exporter := range r.exporters {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+		err := formats.ExportContext(ctx, exporter, books)
+		r.logged[i] = logOutputs(exporter, r.logged[i])
+		if r.manifest != nil {
+			if merr := r.manifest.record(exporter, err); merr != nil && err == nil {
+				err = merr
+			}
+		}
+		if err == nil && r.validate {
+			if v, ok := exporter.(formats.OutputValidator); ok {
+				if verr := v.ValidateOutput(); verr != nil {
+					err = fmt.Errorf("output validation failed: %w", verr)
+				}
+			} else if r.debug {
+				log.Printf("DEBUG: format %s has no output syntax to validate", exporter.Name())
+			}
+		}
+		if err != nil {
+			if len(r.exporters) > 1 {
+				err = fmt.Errorf("%s: %w", exporter.Name(), err)
+			}
+			errs = append(errs, err)
 			continue
 		}
-		if _, ok := grouped[title]; !ok {
-			grouped[title] = &formats.Book{Title: title, Author: author, Highlights: []formats.Highlight{}}
-			order = append(order, title)
+		if r.announce {
+			formats.Infof("%s export complete", exporter.Name())
 		}
-		grouped[title].Highlights = append(grouped[title].Highlights, formats.Highlight{Text: text, Date: date})
-	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("row iteration error: %w", err)
 	}
+	return errors.Join(errs...)
+}
 
-	sort.Strings(order)
-	books := make([]formats.Book, 0, len(order))
-	for _, t := range order {
-		books = append(books, *grouped[t])
+// logOutputs logs, at verbose level, the outputs the exporter reported beyond the first
+// seen ones and returns the new count.
+func logOutputs(exporter formats.Format, seen int) int {
+	lister, ok := exporter.(formats.OutputLister)
+	if !ok {
+		return seen
+	}
+	outputs := lister.Outputs()
+	for _, o := range outputs[min(seen, len(outputs)):] {
+		if o.Path != "" {
+			formats.Verbosef("wrote %s", o.Path)
+		} else {
+			formats.Verbosef("created %s", o.URL)
+		}
 	}
-	return books, nil
+	return len(outputs)
 }
 
-// printConsolePreview prints a deterministic summary to stdout.
-func printConsolePreview(books []formats.Book) {
+// printDryRun lists what an export would contain: each book with its highlight count and a total.
+func printDryRun(exporters []formats.Format, books []formats.Book) {
+	names := make([]string, len(exporters))
+	for i, e := range exporters {
+		names[i] = e.Name()
+	}
+	if len(names) == 1 {
+		fmt.Printf("dry run: format %s would export\n", names[0])
+	} else {
+		fmt.Printf("dry run: formats %s would export\n", strings.Join(names, ", "))
+	}
 	for _, b := range books {
-		fmt.Println("====================")
+		name := b.Title
 		if b.Author != "" {
-			fmt.Printf("%s (%s)\n", b.Title, b.Author)
-		} else {
-			fmt.Printf("%s\n", b.Title)
-		}
-		for i, h := range b.Highlights {
-			truncated := truncateClean(h.Text, previewLen)
-			fmt.Printf("  %2d. %s\n", i+1, truncated)
+			name = fmt.Sprintf("%s (%s)", b.Title, b.Author)
 		}
-		fmt.Println()
+		fmt.Printf("  %s: %d highlights\n", name, len(b.Highlights))
 	}
+	fmt.Printf("dry run: %d highlights in %d books; nothing written\n", countHighlights(books), len(books))
 }
 
 // truncateClean trims whitespace, replaces internal newlines with spaces, and truncates to max characters (rune-safe).
+// The cut moves back to a word boundary when one is close, so words are not split mid-way.
 func truncateClean(s string, max int) string {
 	s = strings.TrimSpace(strings.ReplaceAll(s, "\n", " "))
 	if utf8.RuneCountInString(s) <= max {
@@ -230,5 +551,14 @@ func truncateClean(s string, max int) string {
 	}
 	// iterate runes to safe cut
 	runes := []rune(s)
-	return string(runes[:max]) + "…"
+	cut := max
+	if !unicode.IsSpace(runes[cut]) {
+		for i := cut - 1; i >= max*3/4; i-- {
+			if unicode.IsSpace(runes[i]) {
+				cut = i
+				break
+			}
+		}
+	}
+	return strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace) + "…"
 }
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateClean(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		max  int
+		want string
+	}{
+		{"short text unchanged", "  I must not fear.\n", 40, "I must not fear."},
+		{"newlines become spaces", "line one\nline two", 40, "line one line two"},
+		{"cut moves back to a word boundary", "The quick brown fox jumps", 12, "The quick\xe2\x80\xa6"},
+		{"cut at a space", "The quick brown fox", 9, "The quick\xe2\x80\xa6"},
+		{"one long word is cut mid-word", "Supercalifragilisticexpialidocious", 10, "Supercalif\xe2\x80\xa6"},
+		{"no boundary close enough", "a Supercalifragilisticexpialidocious", 12, "a Supercalif\xe2\x80\xa6"},
+		{"multi-byte runes are not split", "日本語のテキストです", 4, "日本語の\xe2\x80\xa6"},
+		{"four-byte runes are not split", "😀😀😀😀😀", 3, "😀😀😀\xe2\x80\xa6"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateClean(tt.in, tt.max)
+			if got != tt.want {
+				t.Errorf("truncateClean(%q, %d) = %q, want %q", tt.in, tt.max, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("result %q is not valid UTF-8", got)
+			}
+			if utf8.RuneCountInString(tt.in) > tt.max {
+				if !strings.HasSuffix(got, "\xe2\x80\xa6") {
+					t.Errorf("result %q does not end in the U+2026 bytes", got)
+				}
+				if n := utf8.RuneCountInString(strings.TrimSuffix(got, "…")); n > tt.max {
+					t.Errorf("kept %d runes, max %d", n, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSortFlagDefaults(t *testing.T) {
+	tests := []struct {
+		value  string
+		merged bool
+		want   sortSpec
+	}{
+		{"", false, sortSpec{within: sortPosition, books: sortBooksTitle}},
+		{"", true, sortSpec{within: sortDate, books: sortBooksTitle}},
+		{"books=count", true, sortSpec{within: sortDate, books: sortBooksCount}},
+		{"within-book=position", true, sortSpec{within: sortPosition, books: sortBooksTitle}},
+		{"date,books=author", false, sortSpec{within: sortDate, books: sortBooksAuthor}},
+	}
+	for _, tt := range tests {
+		got, err := parseSortFlag(tt.value, tt.merged)
+		if err != nil {
+			t.Fatalf("parseSortFlag(%q, %v): %v", tt.value, tt.merged, err)
+		}
+		if got != tt.want {
+			t.Errorf("parseSortFlag(%q, %v) = %+v, want %+v", tt.value, tt.merged, got, tt.want)
+		}
+	}
+}
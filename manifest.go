@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ozmodiar/kobo-highlights/formats"
+)
+
+// manifestEntry records what one format produced in a run.
+type manifestEntry struct {
+	Format  string           `json:"format"`
+	Status  string           `json:"status"` // "ok" or "failed"
+	Error   string           `json:"error,omitempty"`
+	Outputs []manifestOutput `json:"outputs"`
+}
+
+// manifestOutput is a written file (with its size) or a created remote page.
+type manifestOutput struct {
+	Path string `json:"path,omitempty"`
+	URL  string `json:"url,omitempty"`
+	Size int64  `json:"size,omitempty"`
+}
+
+// manifest collects entries and rewrites the file after every format, so a failure
+// later in the run still leaves a record of what completed.
+type manifest struct {
+	path    string
+	Entries []manifestEntry `json:"formats"`
+}
+
+// record adds the outcome of exporter's Export call and rewrites the manifest.
+func (m *manifest) record(exporter formats.Format, exportErr error) error {
+	entry := manifestEntry{Format: exporter.Name(), Status: "ok", Outputs: []manifestOutput{}}
+	if exportErr != nil {
+		entry.Status, entry.Error = "failed", exportErr.Error()
+	}
+	if lister, ok := exporter.(formats.OutputLister); ok {
+		for _, o := range lister.Outputs() {
+			out := manifestOutput{Path: o.Path, URL: o.URL}
+			if o.Path != "" {
+				if fi, err := os.Stat(o.Path); err == nil {
+					out.Size = fi.Size()
+				}
+			}
+			entry.Outputs = append(entry.Outputs, out)
+		}
+	}
+	m.Entries = append(m.Entries, entry)
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode manifest: %w", err)
+	}
+	if err := os.WriteFile(m.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Snapshot files are hash stores named by UTC run time, so a lexical sort is chronological.
+const (
+	snapshotPrefix = "snapshot-"
+	snapshotSuffix = ".txt"
+	snapshotLayout = "20060102T150405Z"
+)
+
+// latestSnapshot loads the newest snapshot in dir. It returns an empty store and path
+// "" when the directory has none yet.
+func latestSnapshot(dir string) (string, map[string]bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", nil, fmt.Errorf("read snapshot dir: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), snapshotPrefix) && strings.HasSuffix(e.Name(), snapshotSuffix) {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) == 0 {
+		return "", map[string]bool{}, nil
+	}
+	sort.Strings(names)
+	path := filepath.Join(dir, names[len(names)-1])
+	store, err := loadHashStore(path)
+	return path, store, err
+}
+
+// diffSnapshot counts hashes in current but not in previous (added) and the reverse (removed).
+func diffSnapshot(previous, current map[string]bool) (added, removed int) {
+	for h := range current {
+		if !previous[h] {
+			added++
+		}
+	}
+	for h := range previous {
+		if !current[h] {
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// saveSnapshot writes store as a new timestamped snapshot in dir and returns its path.
+func saveSnapshot(dir string, store map[string]bool, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create snapshot dir: %w", err)
+	}
+	path := filepath.Join(dir, snapshotPrefix+now.UTC().Format(snapshotLayout)+snapshotSuffix)
+	return path, saveHashStore(path, store)
+}
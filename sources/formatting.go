@@ -0,0 +1,96 @@
+package sources
+
+import (
+	"encoding/json"
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ozmodiar/kobo-highlights/formats"
+)
+
+// emphasisTag matches any markup tag; only b/strong/i/em change emphasis, the rest are dropped.
+var emphasisTag = regexp.MustCompile(`<(/?)([a-zA-Z][a-zA-Z0-9]*)[^>]*>`)
+
+// parseEmphasisRuns extracts bold/italic runs from a bookmark's ExtraAnnotationData.
+// The payload varies between firmware versions, so parsing is defensive: it accepts
+// an HTML fragment, a JSON string holding one, or a JSON object with an "html" or
+// "markup" field. Runs are only returned when they contain some emphasis and their
+// text matches the highlight text (ignoring whitespace); otherwise nil.
+func parseEmphasisRuns(extra []byte, text string) []formats.TextRun {
+	markup := emphasisMarkup(extra)
+	if markup == "" {
+		return nil
+	}
+	var runs []formats.TextRun
+	bold, italic := 0, 0
+	emphasised := false
+	add := func(s string) {
+		s = html.UnescapeString(s)
+		if s == "" {
+			return
+		}
+		r := formats.TextRun{Text: s, Bold: bold > 0, Italic: italic > 0}
+		if r.Bold || r.Italic {
+			emphasised = true
+		}
+		if n := len(runs); n > 0 && runs[n-1].Bold == r.Bold && runs[n-1].Italic == r.Italic {
+			runs[n-1].Text += s
+			return
+		}
+		runs = append(runs, r)
+	}
+	last := 0
+	for _, m := range emphasisTag.FindAllStringSubmatchIndex(markup, -1) {
+		add(markup[last:m[0]])
+		last = m[1]
+		step := 1
+		if markup[m[2]:m[3]] == "/" {
+			step = -1
+		}
+		switch strings.ToLower(markup[m[4]:m[5]]) {
+		case "b", "strong":
+			bold = max(bold+step, 0)
+		case "i", "em":
+			italic = max(italic+step, 0)
+		}
+	}
+	add(markup[last:])
+	if !emphasised {
+		return nil
+	}
+	var sb strings.Builder
+	for _, r := range runs {
+		sb.WriteString(r.Text)
+	}
+	if strings.Join(strings.Fields(sb.String()), " ") != strings.Join(strings.Fields(text), " ") {
+		return nil
+	}
+	return runs
+}
+
+// emphasisMarkup returns the markup string carried by the payload, or "" if none.
+func emphasisMarkup(extra []byte) string {
+	if len(extra) == 0 || !utf8.Valid(extra) {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(extra, &s) == nil {
+		extra = []byte(s)
+	} else {
+		var obj map[string]any
+		if json.Unmarshal(extra, &obj) == nil {
+			for _, key := range []string{"html", "markup"} {
+				if v, ok := obj[key].(string); ok {
+					return v
+				}
+			}
+			return ""
+		}
+	}
+	if !strings.Contains(string(extra), "<") {
+		return ""
+	}
+	return string(extra)
+}
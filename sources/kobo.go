@@ -0,0 +1,532 @@
+package sources
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/text/language"
+
+	"github.com/ozmodiar/kobo-highlights/formats"
+)
+
+// KoboSource reads highlights from one or more KoboReader.sqlite databases (e.g. of
+// several devices); books from different databases are merged by title and author.
+type KoboSource struct {
+	DBPaths []string
+	Query   string // replaces the built-in query when set (see readCustomQuery)
+}
+
+func (k *KoboSource) Name() string { return "kobo" }
+
+// Stream hands each book to emit as soon as all its rows are read, in the query's
+// (byte-wise) title order, so the library is never held in memory as a whole. It needs
+// a single database and the built-in query: merging devices or grouping the rows of a
+// custom query takes every row first.
+func (k *KoboSource) Stream(opts ReadOptions, emit func(formats.Book) error) error {
+	if len(k.DBPaths) != 1 || k.Query != "" {
+		return fmt.Errorf("--stream needs a single --kobo-db and the built-in query (no --query-file)")
+	}
+	db, err := openKoboDB(k.DBPaths[0], opts)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return streamKoboBooks(db, opts, emit)
+}
+
+// Merges reports whether several databases are read, whose reading positions cannot be
+// compared across devices.
+func (k *KoboSource) Merges() bool { return len(k.DBPaths) > 1 }
+
+func (k *KoboSource) Read(opts ReadOptions) ([]formats.Book, error) {
+	if len(k.DBPaths) == 1 {
+		return readKoboBooks(k.DBPaths[0], k.Query, opts)
+	}
+	var all []formats.Book
+	for _, p := range k.DBPaths {
+		books, err := readKoboBooks(p, k.Query, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		all = append(all, books...)
+	}
+	return mergeBooks(all, opts.Locale), nil
+}
+
+// mergeBooks combines books with the same title and author, dropping highlights that
+// appear more than once (same content hash). Each book keeps the first database's
+// highlights, then the ones only later databases have; --sort orders them. Books stay
+// sorted by title, then author (collated for locale).
+func mergeBooks(books []formats.Book, locale language.Tag) []formats.Book {
+	merged := make(map[string]*formats.Book)
+	seen := make(map[string]bool)
+	order := make([]string, 0, len(books))
+	for _, b := range books {
+		key := b.Title + "\x00" + b.Author
+		m, ok := merged[key]
+		if !ok {
+			c := b
+			c.Highlights = nil
+			m = &c
+			merged[key] = m
+			order = append(order, key)
+		}
+		if b.Progress > m.Progress {
+			m.Progress = b.Progress
+		}
+		for _, h := range b.Highlights {
+			if hash := formats.HighlightHash(b, h); !seen[hash] {
+				seen[hash] = true
+				m.Highlights = append(m.Highlights, h)
+			}
+		}
+	}
+	out := make([]formats.Book, 0, len(order))
+	for _, key := range order {
+		out = append(out, *merged[key])
+	}
+	sortBooks(out, locale)
+	return out
+}
+
+// readKoboBooks opens the database file read-only and reads its books, with query in
+// place of the built-in query when non-empty.
+func readKoboBooks(dbPath, query string, opts ReadOptions) ([]formats.Book, error) {
+	db, err := openKoboDB(dbPath, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	if query != "" {
+		return readCustomQuery(db, query, opts)
+	}
+	return ReadKoboBooks(db, opts)
+}
+
+// openKoboDB checks that the database file exists and opens it read-only.
+func openKoboDB(dbPath string, opts ReadOptions) (*sql.DB, error) {
+	debug := opts.Debug
+	// Ensure the file exists before opening; opening a non-existent file without read-only mode would create an empty DB.
+	if fi, err := os.Stat(dbPath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("database file not found: %s", dbPath)
+		}
+		return nil, fmt.Errorf("unable to stat database file: %w", err)
+	} else if fi.Size() < 1024 { // heuristic: Kobo DBs are typically several MB; extremely small likely wrong file
+		log.Printf("warning: database file is very small (%d bytes) – is this the correct KoboReader.sqlite?", fi.Size())
+		if debug {
+			log.Printf("DEBUG: db=%s size=%d bytes (suspiciously small)", dbPath, fi.Size())
+		}
+	} else if debug {
+		log.Printf("DEBUG: db=%s size=%d bytes", dbPath, fi.Size())
+	}
+
+	// Open read-only to avoid accidental creation or modification of the device's database.
+	// For a one-shot read, immutable=1 skips SQLite's locking, so reading works while the
+	// device holds a lock (e.g. during a sync). A live database (--watch) is being written
+	// to, and immutable=1 would make SQLite ignore those writes (and the WAL) and possibly
+	// read a half-written page, so it is read with normal locking and a busy timeout
+	// instead. Use a URI so we can set pragmas; no escaping needed for simple paths.
+	dsn := fmt.Sprintf("file:%s?mode=ro&immutable=1&_busy_timeout=5000", filepath.Clean(dbPath))
+	if opts.Live {
+		dsn = fmt.Sprintf("file:%s?mode=ro&_busy_timeout=5000", filepath.Clean(dbPath))
+	}
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return db, nil
+}
+
+// IsLocked reports whether err is SQLite's busy or locked error, as returned while the
+// device holds a write lock on a database opened for --watch.
+func IsLocked(err error) bool {
+	var se sqlite3.Error
+	return errors.As(err, &se) && (se.Code == sqlite3.ErrBusy || se.Code == sqlite3.ErrLocked)
+}
+
+// ReadKoboBooks reads highlights from an open KoboReader.sqlite database and groups
+// them into books (title, then author order; highlights in reading order). It is the
+// query logic behind the kobo source, usable without the CLI.
+func ReadKoboBooks(db *sql.DB, opts ReadOptions) ([]formats.Book, error) {
+	var books []formats.Book
+	err := streamKoboBooks(db, opts, func(b formats.Book) error {
+		books = append(books, b)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortBooks(books, opts.Locale)
+	return books, nil
+}
+
+// streamKoboBooks runs the built-in query and passes each book to emit once its rows
+// are complete. Rows come ordered by title, so the books sharing a title (by different
+// authors) are collected until the title changes and then emitted in author order.
+func streamKoboBooks(db *sql.DB, opts ReadOptions, emit func(formats.Book) error) error {
+	limit, debug := opts.Limit, opts.Debug
+	ctx := opts.context()
+	loc := opts.Location
+	// Verify Bookmark table exists before running main query.
+	var tableName string
+	err := db.QueryRowContext(ctx, `SELECT name FROM sqlite_master WHERE type='table' AND name='Bookmark'`).Scan(&tableName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "no such table") {
+			// List available tables for diagnostics.
+			rows, listErr := db.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type='table' ORDER BY name`)
+			available := []string{}
+			if listErr == nil {
+				defer rows.Close()
+				for rows.Next() {
+					var n string
+					if scanErr := rows.Scan(&n); scanErr == nil {
+						available = append(available, n)
+					}
+				}
+			}
+			if debug {
+				log.Printf("DEBUG: Bookmark table missing; available tables: %s", strings.Join(available, ", "))
+			}
+			hint := "Ensure you passed the KoboReader.sqlite from the device (not BookReader.sqlite or another file)."
+			if len(available) == 0 {
+				hint += " No tables were found – the file might be empty or corrupted."
+			} else {
+				hint += " Available tables: " + strings.Join(available, ", ")
+			}
+			return fmt.Errorf("required table 'Bookmark' not found. %s", hint)
+		}
+		return fmt.Errorf("failed to inspect schema: %w", err)
+	}
+	if debug {
+		log.Printf("DEBUG: Found Bookmark table")
+	}
+
+	// Older firmware has no ExtraAnnotationData column; select NULL there.
+	extraCol := "NULL"
+	if opts.PreserveFormatting {
+		if hasColumn(ctx, db, "Bookmark", "ExtraAnnotationData") {
+			extraCol = "b.ExtraAnnotationData"
+		} else if debug {
+			log.Printf("DEBUG: Bookmark.ExtraAnnotationData missing; formatting not available")
+		}
+	}
+
+	// Older firmware has no Color column either.
+	colorCol := "NULL"
+	if hasColumn(ctx, db, "Bookmark", "Color") {
+		colorCol = "b.Color"
+	}
+
+	// Cover image IDs are missing from some firmware versions' content table.
+	imageCol := "NULL"
+	if hasColumn(ctx, db, "content", "ImageId") {
+		imageCol = "c.ImageId"
+	}
+
+	// Reading progress: finished books count as 100%; without ___PercentRead the others
+	// read as 0.
+	progressCol := "CASE WHEN c.ReadStatus = 2 THEN 100 ELSE 0 END"
+	if hasColumn(ctx, db, "content", "___PercentRead") {
+		progressCol = "CASE WHEN c.ReadStatus = 2 THEN 100 ELSE COALESCE(c.___PercentRead, 0) END"
+	}
+
+	// Book metadata columns come and go between firmware versions; a missing one reads as "".
+	seriesCol := contentColumn(ctx, db, "Series")
+	seriesNumberCol := contentColumn(ctx, db, "SeriesNumber")
+	languageCol := contentColumn(ctx, db, "Language")
+	isbnCol := contentColumn(ctx, db, "ISBN")
+	publisherCol := contentColumn(ctx, db, "Publisher")
+
+	// Removed highlights stay in the table with Hidden set; older firmware has no such column.
+	hiddenFilter := ""
+	if !opts.IncludeHidden && hasColumn(ctx, db, "Bookmark", "Hidden") {
+		hiddenFilter = " AND (b.Hidden IS NULL OR b.Hidden = 'false' OR b.Hidden = 0)"
+	}
+
+	baseQuery := `
+		SELECT c.ContentID, c.Title, COALESCE(c.Attribution, ''), b.Text, COALESCE(b.Annotation, ''), b.DateCreated,
+		       ` + progressCol + `,
+		       COALESCE(` + seriesCol + `, ''), COALESCE(` + seriesNumberCol + `, ''), COALESCE(` + languageCol + `, ''),
+		       COALESCE(` + isbnCol + `, ''), COALESCE(` + publisherCol + `, ''), COALESCE(` + imageCol + `, ''),
+		       ` + extraCol + `, ` + colorCol + `,
+		       COALESCE((SELECT ch.Title FROM content ch
+		                 WHERE ch.ContentType = 899 AND ch.BookID = b.VolumeID AND ch.ContentID LIKE b.ContentID || '%'
+		                 ORDER BY ch.VolumeIndex LIMIT 1), '')
+		FROM Bookmark b
+		JOIN content c ON c.ContentID = b.VolumeID
+		WHERE b.Text IS NOT NULL AND LENGTH(TRIM(b.Text)) > 0` + hiddenFilter + `
+		ORDER BY c.Title ASC,
+		         b.ContentID ASC,
+		         CAST(SUBSTR(b.StartContainerPath, INSTR(b.StartContainerPath, '.')+1,
+		              INSTR(SUBSTR(b.StartContainerPath, INSTR(b.StartContainerPath, '.')+1), '.')-1) AS INTEGER) ASC,
+		         b.StartOffset ASC`
+
+	var rows *sql.Rows
+	if limit > 0 {
+		q := baseQuery + " LIMIT ?"
+		rows, err = db.QueryContext(ctx, q, limit)
+	} else {
+		rows, err = db.QueryContext(ctx, baseQuery)
+	}
+	if err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var groups bookGroups
+	runTitle := ""
+	flush := func() error {
+		for _, b := range groups.sorted(opts.Locale) {
+			if err := emit(b); err != nil {
+				return err
+			}
+		}
+		groups = bookGroups{}
+		return nil
+	}
+	for rows.Next() {
+		var contentID, title, author, text, note, date, series, seriesNumber, language, isbn, publisher, imageID, chapter string
+		var progress int
+		var extra []byte
+		var color sql.NullInt64
+		if err := rows.Scan(&contentID, &title, &author, &text, &note, &date, &progress, &series, &seriesNumber, &language, &isbn, &publisher, &imageID, &extra, &color, &chapter); err != nil {
+			log.Printf("failed to scan row: %v", err)
+			continue
+		}
+		if title != runTitle {
+			if err := flush(); err != nil {
+				return err
+			}
+			runTitle = title
+		}
+		b := groups.book(title, author, func() formats.Book {
+			return formats.Book{Series: series, SeriesNumber: seriesNumber, Language: language, ISBN: strings.TrimSpace(isbn), Publisher: strings.TrimSpace(publisher), Progress: progress, StoreURL: koboStoreURL(contentID, title, author), CoverURL: koboCoverURL(contentID, imageID)}
+		})
+		t, _ := formats.ParseKoboDate(date, loc)
+		h := formats.Highlight{Text: text, Note: strings.TrimSpace(note), Chapter: chapter, Date: date, Time: t, Runs: parseEmphasisRuns(extra, text)}
+		if color.Valid {
+			h.Color = formats.KoboColorName(color.Int64)
+		}
+		b.Highlights = append(b.Highlights, h)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("row iteration error: %w", err)
+	}
+	return flush()
+}
+
+// customQueryColumns are the columns a --query-file query must return, in order.
+var customQueryColumns = []string{"title", "author", "text", "date"}
+
+// readCustomQuery runs a user-supplied query in place of the built-in one. It must return
+// the customQueryColumns (any names, this order); rows are grouped like the built-in query's.
+func readCustomQuery(db *sql.DB, query string, opts ReadOptions) ([]formats.Book, error) {
+	loc := opts.Location
+	rows, err := db.QueryContext(opts.context(), query)
+	if err != nil {
+		return nil, fmt.Errorf("custom query failed: %w", err)
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("custom query columns: %w", err)
+	}
+	if len(cols) != len(customQueryColumns) {
+		return nil, fmt.Errorf("custom query returns %d columns (%s); it must return %d: %s",
+			len(cols), strings.Join(cols, ", "), len(customQueryColumns), strings.Join(customQueryColumns, ", "))
+	}
+	var groups bookGroups
+	n := 0
+	for rows.Next() {
+		if opts.Limit > 0 && n >= opts.Limit {
+			break
+		}
+		var title, author, text, date sql.NullString
+		if err := rows.Scan(&title, &author, &text, &date); err != nil {
+			return nil, fmt.Errorf("custom query row: %w", err)
+		}
+		if strings.TrimSpace(text.String) == "" {
+			continue
+		}
+		n++
+		b := groups.book(title.String, author.String, nil)
+		t, _ := formats.ParseKoboDate(date.String, loc)
+		b.Highlights = append(b.Highlights, formats.Highlight{Text: text.String, Date: date.String, Time: t})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return groups.sorted(opts.Locale), nil
+}
+
+// bookGroups collects query rows into books keyed on title and author: different books
+// can share a title ("Selected Poems").
+type bookGroups struct {
+	grouped map[string]*formats.Book
+	order   []string
+}
+
+// book returns the book for title and author, creating it on first use with the
+// metadata from meta (when non-nil).
+func (g *bookGroups) book(title, author string, meta func() formats.Book) *formats.Book {
+	key := title + "\x00" + author
+	if b, ok := g.grouped[key]; ok {
+		return b
+	}
+	if g.grouped == nil {
+		g.grouped = make(map[string]*formats.Book)
+	}
+	var b formats.Book
+	if meta != nil {
+		b = meta()
+	}
+	b.Title, b.Author, b.Highlights = title, author, []formats.Highlight{}
+	g.grouped[key] = &b
+	g.order = append(g.order, key)
+	return &b
+}
+
+// sorted returns the books in title, then author order (collated for locale);
+// highlights keep row order.
+func (g *bookGroups) sorted(locale language.Tag) []formats.Book {
+	books := make([]formats.Book, 0, len(g.order))
+	for _, key := range g.order {
+		books = append(books, *g.grouped[key])
+	}
+	sortBooks(books, locale)
+	return books
+}
+
+// koboStoreURL returns a kobo.com link for a store-purchased book. Sideloaded books
+// have a file:// content ID and no store page. The database keeps no product slug, so
+// the link is a store search for the title and author.
+func koboStoreURL(contentID, title, author string) string {
+	if contentID == "" || strings.HasPrefix(contentID, "file://") {
+		return ""
+	}
+	return "https://www.kobo.com/search?query=" + url.QueryEscape(strings.TrimSpace(title+" "+author))
+}
+
+// koboCoverURL returns the Kobo CDN image for a store-purchased book's ImageId.
+// Sideloaded books' covers only exist on the device (their ImageId is derived from
+// the file path), so they get none.
+func koboCoverURL(contentID, imageID string) string {
+	imageID = strings.TrimSpace(imageID)
+	if imageID == "" || strings.HasPrefix(contentID, "file://") || strings.HasPrefix(imageID, "file_") {
+		return ""
+	}
+	return "https://cdn.kobo.com/book-images/" + url.PathEscape(imageID) + "/353/569/90/False/image.jpg"
+}
+
+// contentColumn returns c.<column> for the query, or NULL when the content table lacks it.
+func contentColumn(ctx context.Context, db *sql.DB, column string) string {
+	if hasColumn(ctx, db, "content", column) {
+		return "c." + column
+	}
+	return "NULL"
+}
+
+// hasColumn reports whether table has the named column.
+func hasColumn(ctx context.Context, db *sql.DB, table, column string) bool {
+	rows, err := db.QueryContext(ctx, `SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return false
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if rows.Scan(&name) == nil && strings.EqualFold(name, column) {
+			return true
+		}
+	}
+	return false
+}
+
+// registration
+type koboQueryFileFlag struct{}
+
+func (koboQueryFileFlag) CLIFlag() any {
+	return &cli.StringFlag{Name: "query-file", Usage: "SQL file replacing the built-in Kobo query; it must return title, author, text, date"}
+}
+
+type koboDBFlag struct{}
+
+func (koboDBFlag) CLIFlag() any {
+	return &cli.StringSliceFlag{Name: "kobo-db", Usage: "Path to a KoboReader.sqlite file; repeat to merge several devices (default: detect a mounted Kobo or ./KoboReader.sqlite)"}
+}
+
+// koboDBCandidates lists where a KoboReader.sqlite is looked for when --kobo-db is
+// omitted: the usual mount points of a connected device, then the current directory.
+func koboDBCandidates() []string {
+	const rel = ".kobo/KoboReader.sqlite"
+	user := os.Getenv("USER")
+	var paths []string
+	if user != "" {
+		paths = append(paths,
+			filepath.Join("/media", user, "KOBOeReader", rel),
+			filepath.Join("/run/media", user, "KOBOeReader", rel))
+	}
+	paths = append(paths, filepath.Join("/media/KOBOeReader", rel), filepath.Join("/Volumes/KOBOeReader", rel))
+	if runtime.GOOS == "windows" {
+		for d := 'D'; d <= 'Z'; d++ {
+			paths = append(paths, string(d)+`:\.kobo\KoboReader.sqlite`)
+		}
+	}
+	return append(paths, "KoboReader.sqlite", rel)
+}
+
+// detectKoboDB returns the first existing candidate database path.
+func detectKoboDB() (string, error) {
+	candidates := koboDBCandidates()
+	for _, p := range candidates {
+		if fi, err := os.Stat(p); err == nil && !fi.IsDir() {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("no Kobo database found (looked in %s); connect the device or pass --kobo-db", strings.Join(candidates, ", "))
+}
+
+func init() {
+	RegisterSource(&SourceFactory{
+		Name:  "kobo",
+		Flags: []formats.FlagProvider{koboDBFlag{}, koboQueryFileFlag{}},
+		Build: func(r formats.FlagValueResolver) (Source, error) {
+			var paths []string
+			for _, p := range r.StringSlice("kobo-db") {
+				if p = strings.TrimSpace(p); p != "" {
+					paths = append(paths, p)
+				}
+			}
+			if len(paths) == 0 {
+				detected, err := detectKoboDB()
+				if err != nil {
+					return nil, err
+				}
+				formats.Infof("using Kobo database %s", detected)
+				paths = []string{detected}
+			}
+			var query string
+			if path := strings.TrimSpace(r.String("query-file")); path != "" {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return nil, fmt.Errorf("read --query-file: %w", err)
+				}
+				if query = strings.TrimSpace(string(data)); query == "" {
+					return nil, fmt.Errorf("--query-file %s is empty", path)
+				}
+			}
+			return &KoboSource{DBPaths: paths, Query: query}, nil
+		},
+	})
+}
@@ -0,0 +1,218 @@
+package sources
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// koboSchema is the part of the device's schema the built-in query reads. The Hidden
+// column is added by writeKoboDB when asked for, as newer firmware has it.
+const koboSchema = `
+CREATE TABLE content (
+	ContentID TEXT PRIMARY KEY, ContentType INTEGER, BookID TEXT, Title TEXT, Attribution TEXT,
+	ReadStatus INTEGER, ___PercentRead INTEGER, Series TEXT, SeriesNumber TEXT, Language TEXT,
+	ISBN TEXT, Publisher TEXT, VolumeIndex INTEGER
+);
+CREATE TABLE Bookmark (
+	BookmarkID TEXT PRIMARY KEY, VolumeID TEXT, ContentID TEXT, Text TEXT, Annotation TEXT,
+	DateCreated TEXT, StartContainerPath TEXT, StartOffset INTEGER
+);
+INSERT INTO content (ContentID, ContentType, Title, Attribution, ReadStatus, ___PercentRead)
+	VALUES ('book-1', 6, 'Dune', 'Frank Herbert', 1, 40);
+`
+
+// writeKoboDB creates a KoboReader.sqlite fixture in a temp dir and returns its path.
+// Each row is a bookmark of book-1 with the given text; with hidden, the Bookmark table
+// gets a Hidden column and rows whose text starts with "hidden" are marked hidden.
+func writeKoboDB(t *testing.T, hidden bool, texts ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "KoboReader.sqlite")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(koboSchema); err != nil {
+		t.Fatal(err)
+	}
+	if hidden {
+		if _, err := db.Exec(`ALTER TABLE Bookmark ADD COLUMN Hidden TEXT`); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, text := range texts {
+		if err := insertBookmark(db, i, text, hidden); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Pad the file past openKoboDB's "suspiciously small" warning.
+	if _, err := db.Exec(`CREATE TABLE padding (x BLOB); INSERT INTO padding VALUES (zeroblob(4096))`); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func insertBookmark(db *sql.DB, i int, text string, hidden bool) error {
+	q := `INSERT INTO Bookmark (BookmarkID, VolumeID, ContentID, Text, DateCreated, StartContainerPath, StartOffset)
+		VALUES (?, 'book-1', 'book-1#ch1', ?, ?, 'span#kobo.1.1', ?)`
+	args := []any{fmt.Sprintf("bm-%d", i), text, fmt.Sprintf("2023-05-01T10:00:%02d.000", i), i}
+	if hidden {
+		q = `INSERT INTO Bookmark (BookmarkID, VolumeID, ContentID, Text, DateCreated, StartContainerPath, StartOffset, Hidden)
+			VALUES (?, 'book-1', 'book-1#ch1', ?, ?, 'span#kobo.1.1', ?, ?)`
+		h := "false"
+		if strings.HasPrefix(text, "hidden") {
+			h = "true"
+		}
+		args = append(args, h)
+	}
+	_, err := db.Exec(q, args...)
+	return err
+}
+
+// highlightTexts returns the texts of all highlights read, in order.
+func highlightTexts(t *testing.T, path string, opts ReadOptions) []string {
+	t.Helper()
+	books, err := (&KoboSource{DBPaths: []string{path}}).Read(opts)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	var texts []string
+	for _, b := range books {
+		for _, h := range b.Highlights {
+			texts = append(texts, h.Text)
+		}
+	}
+	return texts
+}
+
+func TestReadOnlyOpenReturnsHighlights(t *testing.T) {
+	path := writeKoboDB(t, false, "I must not fear.", "Fear is the mind-killer.")
+	before, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, live := range []bool{false, true} {
+		got := highlightTexts(t, path, ReadOptions{Live: live, Location: time.UTC})
+		if len(got) != 2 || got[0] != "I must not fear." || got[1] != "Fear is the mind-killer." {
+			t.Errorf("live=%v: highlights = %q", live, got)
+		}
+	}
+	after, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !after.ModTime().Equal(before.ModTime()) || after.Size() != before.Size() {
+		t.Error("reading modified the database file")
+	}
+	for _, suffix := range []string{"-journal", "-wal"} {
+		if _, err := os.Stat(path + suffix); err == nil {
+			t.Errorf("reading created %s", filepath.Base(path+suffix))
+		}
+	}
+}
+
+// A live read (--watch) must see rows the device wrote since the last poll even when
+// they still sit in the write-ahead log, which an immutable open ignores.
+func TestLiveReadSeesUncheckpointedWrites(t *testing.T) {
+	path := writeKoboDB(t, false, "I must not fear.")
+	writer, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+	writer.SetMaxOpenConns(1) // keep the connection, and with it the WAL, open
+	if _, err := writer.Exec(`PRAGMA journal_mode=WAL; PRAGMA wal_autocheckpoint=0`); err != nil {
+		t.Fatal(err)
+	}
+	if err := insertBookmark(writer, 1, "Fear is the mind-killer.", false); err != nil {
+		t.Fatal(err)
+	}
+	if got := highlightTexts(t, path, ReadOptions{Live: true, Location: time.UTC}); len(got) != 2 {
+		t.Errorf("live read: highlights = %q, want the new one too", got)
+	}
+}
+
+func TestHiddenHighlights(t *testing.T) {
+	withHidden := writeKoboDB(t, true, "kept one", "hidden one", "kept two")
+	withoutHidden := writeKoboDB(t, false, "kept one", "kept two")
+	tests := []struct {
+		name          string
+		path          string
+		includeHidden bool
+		want          []string
+	}{
+		{"hidden rows are dropped by default", withHidden, false, []string{"kept one", "kept two"}},
+		{"--include-hidden returns them", withHidden, true, []string{"kept one", "hidden one", "kept two"}},
+		{"firmware without a Hidden column", withoutHidden, false, []string{"kept one", "kept two"}},
+		{"--include-hidden without a Hidden column", withoutHidden, true, []string{"kept one", "kept two"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := highlightTexts(t, tt.path, ReadOptions{IncludeHidden: tt.includeHidden, Location: time.UTC})
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("highlights = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// dropColumns removes columns from a fixture, as older firmware never had them.
+func dropColumns(t *testing.T, path, table string, columns ...string) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, c := range columns {
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, c)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadWithoutMetadataColumns(t *testing.T) {
+	path := writeKoboDB(t, false, "I must not fear.")
+	dropColumns(t, path, "content", "___PercentRead", "Series", "SeriesNumber", "Language", "ISBN", "Publisher")
+	books, err := (&KoboSource{DBPaths: []string{path}}).Read(ReadOptions{Location: time.UTC})
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(books) != 1 || len(books[0].Highlights) != 1 || books[0].Title != "Dune" {
+		t.Fatalf("books = %+v", books)
+	}
+	if b := books[0]; b.Series != "" || b.ISBN != "" || b.Publisher != "" || b.Language != "" || b.Progress != 0 {
+		t.Errorf("missing columns read as %+v, want empty metadata", b)
+	}
+	full, err := (&KoboSource{DBPaths: []string{writeKoboDB(t, false, "I must not fear.")}}).Read(ReadOptions{Location: time.UTC})
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(full) != 1 || full[0].Progress != 40 {
+		t.Errorf("progress with ___PercentRead = %+v, want 40", full)
+	}
+}
+
+func TestIsLocked(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{fmt.Errorf("query failed: %w", sqlite3.Error{Code: sqlite3.ErrBusy}), true},
+		{sqlite3.Error{Code: sqlite3.ErrLocked}, true},
+		{sqlite3.Error{Code: sqlite3.ErrCorrupt}, false},
+		{errors.New("database is locked"), false},
+	} {
+		if got := IsLocked(tc.err); got != tc.want {
+			t.Errorf("IsLocked(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
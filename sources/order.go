@@ -0,0 +1,31 @@
+package sources
+
+import (
+	"sort"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+
+	"github.com/ozmodiar/kobo-highlights/formats"
+)
+
+// sortBooks orders books by title, then author, with the collation rules of locale
+// (language.Und: the root Unicode collation), so case and accents do not scatter titles
+// the way byte order does ("apple" before "Zebra", "Émile" next to "Emile").
+func sortBooks(books []formats.Book, locale language.Tag) {
+	c := collate.New(locale)
+	sort.SliceStable(books, func(i, j int) bool {
+		if r := c.CompareString(books[i].Title, books[j].Title); r != 0 {
+			return r < 0
+		}
+		if r := c.CompareString(books[i].Author, books[j].Author); r != 0 {
+			return r < 0
+		}
+		// Titles equal under collation (e.g. differing in ignorable characters) keep a
+		// stable byte order.
+		if books[i].Title != books[j].Title {
+			return books[i].Title < books[j].Title
+		}
+		return books[i].Author < books[j].Author
+	})
+}
@@ -0,0 +1,78 @@
+package sources
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"golang.org/x/text/language"
+
+	"github.com/ozmodiar/kobo-highlights/formats"
+)
+
+// ReadOptions holds source-independent read settings.
+type ReadOptions struct {
+	Limit              int // exact row limit applied by the source (0 = all)
+	Debug              bool
+	PreserveFormatting bool            // read emphasis runs where the source records them
+	IncludeHidden      bool            // also read highlights the reader deleted (kept by the device as hidden rows)
+	Live               bool            // the database may change while being read (--watch): use locking, not immutable mode
+	Location           *time.Location  // zone of the device's wall-clock timestamps (nil = UTC)
+	Context            context.Context // cancels a running read (nil = never cancelled)
+	Locale             language.Tag    // collation of book titles (zero value: root Unicode order)
+}
+
+// context returns the read's context, defaulting to context.Background.
+func (o ReadOptions) context() context.Context {
+	if o.Context == nil {
+		return context.Background()
+	}
+	return o.Context
+}
+
+// Source defines a pluggable highlight input (the counterpart of formats.Format).
+type Source interface {
+	Read(opts ReadOptions) ([]formats.Book, error)
+	Name() string
+}
+
+// Streamer is implemented by sources that can pass books on one at a time while
+// reading (--stream), instead of returning the whole library at once.
+type Streamer interface {
+	Stream(opts ReadOptions, emit func(formats.Book) error) error
+}
+
+// Merger is implemented by sources that can combine several inputs (e.g. devices). When
+// Merges reports true, a book's highlights come from several inputs and are not in one
+// reading order.
+type Merger interface {
+	Merges() bool
+}
+
+// SourceFactory holds metadata + builder for a source implementation.
+type SourceFactory struct {
+	Name  string
+	Flags []formats.FlagProvider
+	Build func(resolver formats.FlagValueResolver) (Source, error)
+}
+
+var sourceRegistry = map[string]*SourceFactory{}
+
+// RegisterSource adds a source factory to the registry (last one wins on name collision).
+func RegisterSource(f *SourceFactory) { sourceRegistry[f.Name] = f }
+
+// GetSourceFactory returns a factory by name.
+func GetSourceFactory(name string) (*SourceFactory, bool) {
+	f, ok := sourceRegistry[name]
+	return f, ok
+}
+
+// ListSourceNames returns registered source names in sorted order.
+func ListSourceNames() []string {
+	names := make([]string, 0, len(sourceRegistry))
+	for n := range sourceRegistry {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ozmodiar/kobo-highlights/formats"
+)
+
+// printStats writes a summary of books to w: totals, the average highlights per book
+// and the most-highlighted book (the first one on a tie).
+func printStats(w io.Writer, books []formats.Book) {
+	total := countHighlights(books)
+	fmt.Fprintf(w, "books:      %d\n", len(books))
+	fmt.Fprintf(w, "highlights: %d\n", total)
+	if len(books) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "average:    %.1f highlights per book\n", float64(total)/float64(len(books)))
+	top := books[0]
+	for _, b := range books[1:] {
+		if len(b.Highlights) > len(top.Highlights) {
+			top = b
+		}
+	}
+	name := top.Title
+	if top.Author != "" {
+		name = fmt.Sprintf("%s (%s)", top.Title, top.Author)
+	}
+	fmt.Fprintf(w, "most:       %s – %d highlights\n", name, len(top.Highlights))
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/ozmodiar/kobo-highlights/formats"
+	"github.com/ozmodiar/kobo-highlights/sources"
+)
+
+// errStreamLimit stops the source once --limit is reached, errStreamFailed once every
+// format has failed.
+var (
+	errStreamLimit  = errors.New("stream limit reached")
+	errStreamFailed = errors.New("all formats failed")
+)
+
+// streamExport is the --stream path: each book goes from the source through filter to
+// the format as soon as it is read, so the library is never held in memory (useful on
+// the device itself). Books come in the source's title order. Options that need every
+// book before writing the first one are rejected.
+func streamExport(c *cli.Context, source sources.Source, factories []*formats.FormatFactory, opts sources.ReadOptions, spec sortSpec, filter func([]formats.Book) []formats.Book) error {
+	for _, name := range []string{"watch", "diff", "snapshot-dir", "dedupe-db", "dry-run", "stats"} {
+		if c.IsSet(name) {
+			return fmt.Errorf("--stream cannot be combined with --%s", name)
+		}
+	}
+	if spec.books != sortBooksTitle {
+		return fmt.Errorf("--stream keeps the source's title order; it cannot be combined with --sort books=%s", spec.books)
+	}
+	streamer, ok := source.(sources.Streamer)
+	if !ok {
+		return fmt.Errorf("source %s cannot stream", source.Name())
+	}
+	exporters, err := buildExporters(factories, cliResolver{c})
+	if err != nil {
+		return err
+	}
+	outs := make([]formats.BookStreamer, len(exporters))
+	for i, exporter := range exporters {
+		if outs[i], ok = exporter.(formats.BookStreamer); !ok {
+			return fmt.Errorf("format %s cannot stream (it needs all books at once)", exporter.Name())
+		}
+	}
+	// A format that fails is closed and skipped from then on; the others carry on.
+	failed := make([]error, len(outs))
+	open := 0
+	fail := func(i int, err error) {
+		failed[i] = err
+		outs[i].EndStream()
+		open--
+	}
+	for i, out := range outs {
+		open++
+		if err := out.StartStream(); err != nil {
+			fail(i, err)
+		}
+	}
+	limit, total := c.Int("limit"), 0
+	if c.Bool("limit-strict") {
+		limit = 0 // applied by the source
+	}
+	readErr := streamer.Stream(opts, func(b formats.Book) error {
+		if open == 0 {
+			return errStreamFailed
+		}
+		if limit > 0 && total >= limit {
+			return errStreamLimit
+		}
+		books := formats.CountText(limitPerBook(filter([]formats.Book{b}), c.Int("limit-per-book")))
+		if c.Bool("inline-notes") {
+			books = formats.InlineNotes(books, c.String("inline-notes-separator"))
+		}
+		for _, b := range books {
+			for i, out := range outs {
+				if failed[i] == nil {
+					if err := out.StreamBook(b); err != nil {
+						fail(i, err)
+					}
+				}
+			}
+			total += len(b.Highlights)
+		}
+		return nil
+	})
+	if errors.Is(readErr, errStreamLimit) || errors.Is(readErr, errStreamFailed) {
+		readErr = nil
+	}
+	var m *manifest
+	if path := c.String("manifest"); path != "" {
+		m = &manifest{path: path}
+	}
+	var errs []error
+	for i, exporter := range exporters {
+		err := failed[i]
+		if err == nil {
+			err = outs[i].EndStream()
+		}
+		if err == nil {
+			err = readErr
+		}
+		logOutputs(exporter, 0)
+		if m != nil {
+			if merr := m.record(exporter, err); merr != nil && err == nil {
+				err = merr
+			}
+		}
+		if err == nil && c.Bool("validate-output") {
+			if v, ok := exporter.(formats.OutputValidator); ok {
+				if verr := v.ValidateOutput(); verr != nil {
+					err = fmt.Errorf("output validation failed: %w", verr)
+				}
+			}
+		}
+		if err != nil {
+			if len(exporters) > 1 && err != readErr {
+				err = fmt.Errorf("%s: %w", exporter.Name(), err)
+			}
+			errs = append(errs, err)
+			continue
+		}
+		formats.Infof("%s export complete (streamed %d highlights)", exporter.Name(), total)
+	}
+	if readErr != nil {
+		return readErr
+	}
+	return errors.Join(errs...)
+}
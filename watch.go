@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ozmodiar/kobo-highlights/formats"
+	"github.com/ozmodiar/kobo-highlights/sources"
+)
+
+// watchLoop polls read every interval and passes highlights whose hash is not in seen
+// to export, until ctx is cancelled (SIGINT/SIGTERM). A poll that finds the database locked (the device
+// is syncing) is skipped; other read errors end the loop.
+func watchLoop(ctx context.Context, interval time.Duration, seen map[string]bool, read func() ([]formats.Book, error), export func([]formats.Book) error) error {
+	if interval <= 0 {
+		return fmt.Errorf("--watch-interval must be positive")
+	}
+	formats.Infof("watching for new highlights every %s (Ctrl+C to stop)", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		books, err := read()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if sources.IsLocked(err) {
+				log.Printf("database is locked, skipping this poll: %v", err)
+				continue
+			}
+			return err
+		}
+		fresh := skipSeen(books, seen)
+		if len(fresh) == 0 {
+			continue
+		}
+		if err := export(fresh); err != nil {
+			return err
+		}
+		recordHashes(fresh, seen)
+		formats.Infof("%s: exported %d new highlights", time.Now().Format(time.TimeOnly), countHighlights(fresh))
+	}
+}
+
+// countHighlights returns the number of highlights across books.
+func countHighlights(books []formats.Book) int {
+	n := 0
+	for _, b := range books {
+		n += len(b.Highlights)
+	}
+	return n
+}